@@ -0,0 +1,66 @@
+package v4
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const (
+	authorizationHeader = "Authorization"
+	apiKeyHeader        = "X-Api-Key"
+
+	// number of trailing characters Redact leaves readable
+	redactKeep = 4
+)
+
+// Redact masks a secret for display, keeping only its last characters
+// when the secret is long enough for them not to give it away.
+func Redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	r := []rune(secret)
+	if len(r) < 4*redactKeep {
+		return "****"
+	}
+
+	return "****" + string(r[len(r)-redactKeep:])
+}
+
+// RedactHeaders returns a copy of h with credentials masked.
+func RedactHeaders(h http.Header) http.Header {
+	redacted := h.Clone()
+	for _, key := range []string{authorizationHeader, apiKeyHeader} {
+		v := redacted.Get(key)
+		if v == "" {
+			continue
+		}
+		if scheme := strings.SplitN(v, " ", 2); len(scheme) == 2 && key == authorizationHeader {
+			redacted.Set(key, scheme[0]+" "+Redact(scheme[1]))
+		} else {
+			redacted.Set(key, Redact(v))
+		}
+	}
+
+	return redacted
+}
+
+func (c *Client) logRequest(req *http.Request) {
+	if c.reqLog == nil {
+		return
+	}
+
+	h := RedactHeaders(req.Header)
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(" " + k + "=" + strings.Join(h[k], ","))
+	}
+	c.reqLog.Printf("-> %s %s%s", req.Method, req.URL.String(), b.String())
+}
@@ -2,38 +2,81 @@ package v4
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"fmt"
 	"io/ioutil"
+	"log"
 	"net/http"
 	"net/url"
 	"path"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 )
 
-const (
-	basePath  = "demo"
-	serverURL = "http://try.repustate.com:9000"
-)
-
 type Client struct {
+	serverURL  string
 	serverAddr *url.URL
+	basePath   string
+	apiKey     string
+	token      string
+	lang       string
+	httpClient *http.Client
+	timeout    time.Duration
+	retry      RetryPolicy
+	reqLog     *log.Logger
 }
 
-func New() (Client, error) {
-	serverAddr, err := url.Parse(serverURL)
+// New creates a client for the Repustate API. Without options
+// the client talks to the public demo server.
+func New(opts ...Option) (Client, error) {
+	c := Client{
+		serverURL:  DefaultServerURL,
+		basePath:   DefaultBasePath,
+		httpClient: http.DefaultClient,
+		retry:      DefaultRetryPolicy,
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	serverAddr, err := url.Parse(c.serverURL)
 	if err != nil {
 		return Client{}, errors.WithMessage(err, "bad server url address")
 	}
-	return Client{
-		serverAddr: serverAddr,
-	}, nil
+	if serverAddr.Scheme == "" || serverAddr.Host == "" {
+		return Client{}, errors.Errorf("bad server url address %q: scheme and host are required", c.serverURL)
+	}
+	c.serverAddr = serverAddr
+
+	return c, nil
 }
 
-func (c *Client) Index(text, lang, user string) (*IndexResult, error) {
+// ServerURL returns the address of the server the client talks to.
+func (c *Client) ServerURL() string {
+	return c.serverAddr.String()
+}
+
+func (c *Client) Index(text, lang, user string, opts ...IndexOption) (*IndexResult, error) {
+	return c.IndexContext(context.Background(), text, lang, user, opts...)
+}
+
+// IndexContext adds text to the user's index. The request is abandoned
+// as soon as ctx is done.
+func (c *Client) IndexContext(ctx context.Context, text, lang, user string, opts ...IndexOption) (*IndexResult, error) {
+	o := NewIndexOptions(opts...)
+	if o.ID != "" {
+		if _, err := documentEndpoint(o.ID); err != nil {
+			return nil, err
+		}
+	}
 	q := url.Values{}
 	q.Set("username", user)
+	if lang == "" {
+		lang = c.lang
+	}
 	if lang != "" {
 		q.Set("lang", lang)
 	}
@@ -41,12 +84,19 @@ func (c *Client) Index(text, lang, user string) (*IndexResult, error) {
 	data := map[string]interface{}{
 		"text": text,
 	}
+	if o.ID != "" {
+		data["id"] = o.ID
+	}
+	if len(o.Metadata) != 0 {
+		data["metadata"] = o.Metadata
+	}
 	req, err := c.newRequest("index", http.MethodPost, q, data)
 	if err != nil {
 		return nil, err
 	}
 
-	body, err := requestDo(req)
+	// a document indexed twice under its own id is only stored once
+	body, err := c.requestDo(ctx, req, o.ID != "")
 	if err != nil {
 		return nil, err
 	}
@@ -60,16 +110,34 @@ func (c *Client) Index(text, lang, user string) (*IndexResult, error) {
 }
 
 func (c *Client) Search(query, user string) (*SearchResult, error) {
+	return c.SearchContext(context.Background(), query, user)
+}
+
+// SearchContext runs query against the user's index. The request is
+// abandoned as soon as ctx is done.
+func (c *Client) SearchContext(ctx context.Context, query, user string) (*SearchResult, error) {
+	return c.SearchPage(ctx, query, user, Page{})
+}
+
+// SearchPage runs query against the user's index, returning only
+// the matches within page. Total always counts all matches.
+func (c *Client) SearchPage(ctx context.Context, query, user string, page Page) (*SearchResult, error) {
 	q := url.Values{}
 	q.Set("username", user)
 	q.Set("query", query)
+	if page.Limit > 0 {
+		q.Set("limit", strconv.Itoa(page.Limit))
+	}
+	if page.Offset > 0 {
+		q.Set("offset", strconv.Itoa(page.Offset))
+	}
 
 	req, err := c.newRequest("search", http.MethodGet, q, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	body, err := requestDo(req)
+	body, err := c.requestDo(ctx, req, true)
 	if err != nil {
 		return nil, err
 	}
@@ -82,8 +150,110 @@ func (c *Client) Search(query, user string) (*SearchResult, error) {
 	return res, nil
 }
 
+func (c *Client) Get(id, user string) (*Document, error) {
+	return c.GetContext(context.Background(), id, user)
+}
+
+// GetContext fetches the document with the given id from the user's index.
+func (c *Client) GetContext(ctx context.Context, id, user string) (*Document, error) {
+	endpoint, err := documentEndpoint(id)
+	if err != nil {
+		return nil, err
+	}
+	q := url.Values{}
+	q.Set("username", user)
+
+	req, err := c.newRequest(endpoint, http.MethodGet, q, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := c.requestDo(ctx, req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Document{}
+	if err := json.Unmarshal(body, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (c *Client) Update(id, text, lang, user string) (*IndexResult, error) {
+	return c.UpdateContext(context.Background(), id, text, lang, user)
+}
+
+// UpdateContext replaces the text of the document with the given id,
+// the document is analyzed again.
+func (c *Client) UpdateContext(ctx context.Context, id, text, lang, user string) (*IndexResult, error) {
+	endpoint, err := documentEndpoint(id)
+	if err != nil {
+		return nil, err
+	}
+	q := url.Values{}
+	q.Set("username", user)
+	if lang == "" {
+		lang = c.lang
+	}
+	if lang != "" {
+		q.Set("lang", lang)
+	}
+
+	data := map[string]interface{}{
+		"text": text,
+	}
+	req, err := c.newRequest(endpoint, http.MethodPut, q, data)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := c.requestDo(ctx, req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &IndexResult{}
+	if err := json.Unmarshal(body, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (c *Client) Delete(id, user string) error {
+	return c.DeleteContext(context.Background(), id, user)
+}
+
+// DeleteContext removes the document with the given id from the user's index.
+func (c *Client) DeleteContext(ctx context.Context, id, user string) error {
+	endpoint, err := documentEndpoint(id)
+	if err != nil {
+		return err
+	}
+	q := url.Values{}
+	q.Set("username", user)
+
+	req, err := c.newRequest(endpoint, http.MethodDelete, q, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.requestDo(ctx, req, true)
+	return err
+}
+
+func documentEndpoint(id string) (string, error) {
+	if id == "" || strings.ContainsAny(id, "/?#") || id == "." || id == ".." {
+		return "", errors.Errorf("bad document id %q", id)
+	}
+
+	return path.Join("documents", id), nil
+}
+
 func (c *Client) newRequest(endpoint, method string, q url.Values, body interface{}) (*http.Request, error) {
-	rel := &url.URL{Path: path.Join(basePath, endpoint)}
+	rel := &url.URL{Path: path.Join(c.basePath, endpoint)}
 	u := c.serverAddr.ResolveReference(rel)
 	buf := new(bytes.Buffer)
 	if body != nil {
@@ -100,6 +270,11 @@ func (c *Client) newRequest(endpoint, method string, q url.Values, body interfac
 		req.Header.Set("Content-Type", "application/json")
 	}
 	req.Header.Set("Accept", "application/json; charset=utf-8")
+	if c.token != "" {
+		req.Header.Set(authorizationHeader, "Bearer "+c.token)
+	} else if c.apiKey != "" {
+		req.Header.Set(apiKeyHeader, c.apiKey)
+	}
 	if q != nil {
 		req.URL.RawQuery = q.Encode()
 	}
@@ -107,21 +282,75 @@ func (c *Client) newRequest(endpoint, method string, q url.Values, body interfac
 	return req, nil
 }
 
-func requestDo(r *http.Request) ([]byte, error) {
-	resp, err := http.DefaultClient.Do(r)
+// requestDo sends r, retrying it according to the client's retry policy.
+// Every attempt is bound by the client's timeout. Requests which are not
+// idempotent are only retried when the server tells it did not handle them.
+func (c *Client) requestDo(ctx context.Context, r *http.Request, idempotent bool) ([]byte, error) {
+	for retry := 0; ; retry++ {
+		body, wait, err := c.attempt(ctx, r, idempotent)
+		if err == nil {
+			return body, nil
+		}
+		if wait < 0 || retry >= c.retry.MaxRetries || ctx.Err() != nil {
+			return nil, err
+		}
+
+		if backoff := c.retry.backoff(retry); backoff > wait {
+			wait = backoff
+		}
+		if err := sleepCtx(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// attempt sends r once. On failure it also reports how long to wait before
+// the request may be retried, negative when it must not be retried.
+func (c *Client) attempt(ctx context.Context, r *http.Request, idempotent bool) ([]byte, time.Duration, error) {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	req := r.Clone(ctx)
+	if r.GetBody != nil {
+		body, err := r.GetBody()
+		if err != nil {
+			return nil, -1, err
+		}
+		req.Body = body
+	}
+
+	c.logRequest(req)
+	resp, err := c.httpClient.Do(req)
+	if c.reqLog != nil && err == nil {
+		c.reqLog.Printf("<- %s %s: %s", req.Method, req.URL.Path, resp.Status)
+	}
 	if err != nil {
-		return nil, err
+		// the server may have handled a request it did not answer
+		if idempotent && retryableErr(err) {
+			return nil, 0, err
+		}
+		return nil, -1, err
 	}
 	defer resp.Body.Close()
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		if idempotent {
+			return nil, 0, err
+		}
+		return nil, -1, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("server responded %q", resp.Status)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		err := newAPIError(req, resp, body)
+		if retryableStatus(resp.StatusCode) && (idempotent || unhandled(resp)) {
+			return nil, retryAfter(resp), err
+		}
+		return nil, -1, err
 	}
 
-	return body, nil
+	return body, 0, nil
 }
@@ -0,0 +1,148 @@
+package v4_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	v4 "github.com/repustate/rcli/api-client/v4"
+	"github.com/repustate/rcli/api-client/v4/v4test"
+)
+
+func TestClient(t *testing.T) {
+	srv := v4test.NewServer()
+	defer srv.Close()
+	c, err := v4.New(v4.WithServerURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	res, err := c.IndexContext(ctx, "Angela Merkel met Emmanuel Macron in Paris, the weather was good.", "en", "ann",
+		v4.WithMetadata(map[string]string{"source": "news.txt"}))
+	if err != nil {
+		t.Fatalf("IndexContext() error = %v", err)
+	}
+	want := &v4.IndexResult{
+		ID:        "doc-000001",
+		Themes:    []string{"weather"},
+		Sentiment: "pos",
+		Entities: []v4.Entity{
+			{Title: "Angela Merkel", Classifications: []string{"Person.politician"}},
+			{Title: "Emmanuel Macron", Classifications: []string{"Person.politician"}},
+			{Title: "Paris", Classifications: []string{"Location.city"}},
+		},
+	}
+	if !reflect.DeepEqual(res, want) {
+		t.Errorf("IndexContext() = %+v, want %+v", res, want)
+	}
+
+	if _, err := c.IndexContext(ctx, "The weather in Berlin is bad.", "de", "ann", v4.WithDocumentID("berlin")); err != nil {
+		t.Fatalf("IndexContext() with an id error = %v", err)
+	}
+	// users have separate indexes
+	if _, err := c.IndexContext(ctx, "The weather is good.", "en", "bob"); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := c.SearchPage(ctx, "theme:weather AND Person.politician:*", "ann", v4.Page{})
+	if err != nil {
+		t.Fatalf("SearchPage() error = %v", err)
+	}
+	if found.Total != 1 || len(found.Documents) != 1 || found.Documents[0].ID != "doc-000001" || found.Documents[0].Metadata["source"] != "news.txt" {
+		t.Errorf("SearchPage() = %+v", found)
+	}
+	found, err = c.SearchPage(ctx, "theme:weather", "ann", v4.Page{Limit: 1, Offset: 1})
+	if err != nil {
+		t.Fatalf("SearchPage() error = %v", err)
+	}
+	if found.Total != 2 || len(found.Documents) != 1 || found.Documents[0].ID != "berlin" {
+		t.Errorf("SearchPage() of the second page = %+v", found)
+	}
+
+	if _, err := c.UpdateContext(ctx, "berlin", "The weather in Berlin is great.", "en", "ann"); err != nil {
+		t.Fatalf("UpdateContext() error = %v", err)
+	}
+	doc, err := c.GetContext(ctx, "berlin", "ann")
+	if err != nil {
+		t.Fatalf("GetContext() error = %v", err)
+	}
+	if doc.Text != "The weather in Berlin is great." {
+		t.Errorf("GetContext() = %+v after the update", doc)
+	}
+
+	if err := c.DeleteContext(ctx, "berlin", "ann"); err != nil {
+		t.Fatalf("DeleteContext() error = %v", err)
+	}
+	if _, err := c.GetContext(ctx, "berlin", "ann"); !v4.IsNotFound(err) {
+		t.Errorf("GetContext() of a deleted document error = %v, want not found", err)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	srv := v4test.NewServer()
+	defer srv.Close()
+	c, err := v4.New(v4.WithServerURL(srv.URL), v4.WithRetryPolicy(v4.RetryPolicy{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	_, err = c.IndexContext(ctx, "Text.", "xx", "ann")
+	var apiErr *v4.APIError
+	if !v4.IsBadRequest(err) || !errors.As(err, &apiErr) {
+		t.Fatalf("IndexContext() in an unsupported language error = %v, want a bad request", err)
+	}
+	if apiErr.Method != http.MethodPost || apiErr.Endpoint != "/demo/index" || apiErr.Message == "" {
+		t.Errorf("IndexContext() error = %+v", apiErr)
+	}
+
+	if _, err := c.SearchPage(ctx, "bogus", "ann", v4.Page{}); !v4.IsBadRequest(err) {
+		t.Errorf("SearchPage() of a malformed query error = %v, want a bad request", err)
+	}
+	if err := c.DeleteContext(ctx, "missing", "ann"); !v4.IsNotFound(err) {
+		t.Errorf("DeleteContext() of a missing document error = %v, want not found", err)
+	}
+	// ids which would change the endpoint are refused before any request
+	for _, id := range []string{"", "a/b", "..", "a?b"} {
+		if _, err := c.GetContext(ctx, id, "ann"); err == nil || errors.As(err, &apiErr) {
+			t.Errorf("GetContext(%q) error = %v, want a bad document id", id, err)
+		}
+	}
+
+	if _, err := v4.New(v4.WithServerURL("localhost:9000")); err == nil {
+		t.Error("New() with a server url without scheme succeeded")
+	}
+}
+
+func TestClientCredentials(t *testing.T) {
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		w.Write([]byte(`{"total": 0, "matches": []}`))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		opt        v4.Option
+		key, value string
+	}{
+		{v4.WithAPIKey("secret-key"), "X-Api-Key", "secret-key"},
+		{v4.WithBearerToken("secret-token"), "Authorization", "Bearer secret-token"},
+	}
+	for _, tt := range tests {
+		c, err := v4.New(v4.WithServerURL(srv.URL), tt.opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.SearchPage(context.Background(), "theme:weather", "ann", v4.Page{}); err != nil {
+			t.Fatal(err)
+		}
+		if got := header.Get(tt.key); got != tt.value {
+			t.Errorf("%s header = %q, want %q", tt.key, got, tt.value)
+		}
+	}
+}
@@ -0,0 +1,319 @@
+package v4
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// maximum length of text shown in log lines
+	maxLogTextLen = 40
+)
+
+// NewLoggingService logs every call to next along with its duration
+// and outcome.
+func NewLoggingService(next Service, logger *log.Logger) Service {
+	return &loggingService{next: next, log: logger}
+}
+
+type loggingService struct {
+	next Service
+	log  *log.Logger
+}
+
+func (s *loggingService) done(call string, start time.Time, err error) {
+	if err != nil {
+		s.log.Printf("%s failed after %v: %v", call, time.Since(start), err)
+		return
+	}
+	s.log.Printf("%s succeeded in %v", call, time.Since(start))
+}
+
+func (s *loggingService) IndexContext(ctx context.Context, text, lang, user string, opts ...IndexOption) (*IndexResult, error) {
+	start := time.Now()
+	res, err := s.next.IndexContext(ctx, text, lang, user, opts...)
+	call := fmt.Sprintf("index text=%q lang=%q", shorten(text), lang)
+	if o := NewIndexOptions(opts...); o.ID != "" {
+		call += fmt.Sprintf(" id=%q", o.ID)
+	}
+	s.done(call, start, err)
+
+	return res, err
+}
+
+func (s *loggingService) SearchPage(ctx context.Context, query, user string, page Page) (*SearchResult, error) {
+	start := time.Now()
+	res, err := s.next.SearchPage(ctx, query, user, page)
+	s.done(fmt.Sprintf("search query=%q limit=%d offset=%d", query, page.Limit, page.Offset), start, err)
+
+	return res, err
+}
+
+func (s *loggingService) GetContext(ctx context.Context, id, user string) (*Document, error) {
+	start := time.Now()
+	res, err := s.next.GetContext(ctx, id, user)
+	s.done(fmt.Sprintf("get id=%q", id), start, err)
+
+	return res, err
+}
+
+func (s *loggingService) UpdateContext(ctx context.Context, id, text, lang, user string) (*IndexResult, error) {
+	start := time.Now()
+	res, err := s.next.UpdateContext(ctx, id, text, lang, user)
+	s.done(fmt.Sprintf("update id=%q text=%q lang=%q", id, shorten(text), lang), start, err)
+
+	return res, err
+}
+
+func (s *loggingService) DeleteContext(ctx context.Context, id, user string) error {
+	start := time.Now()
+	err := s.next.DeleteContext(ctx, id, user)
+	s.done(fmt.Sprintf("delete id=%q", id), start, err)
+
+	return err
+}
+
+func shorten(s string) string {
+	r := []rune(s)
+	if len(r) > maxLogTextLen {
+		return string(r[:maxLogTextLen]) + "..."
+	}
+
+	return s
+}
+
+// NewCachingService remembers search results and fetched documents for ttl.
+// Indexing, updating or deleting a document drops all cached results of
+// its user. Callers get copies of the cached results, which they may
+// modify.
+func NewCachingService(next Service, ttl time.Duration) Service {
+	return &cachingService{
+		next:  next,
+		ttl:   ttl,
+		users: map[string]*userCache{},
+	}
+}
+
+type cacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// userCache holds the cached results of a user. Its generation changes
+// whenever the documents of the user do, so that the results of calls
+// which started before are not stored.
+type userCache struct {
+	generation uint64
+	entries    map[string]cacheEntry
+}
+
+type cachingService struct {
+	next Service
+	ttl  time.Duration
+
+	mu    sync.Mutex
+	users map[string]*userCache
+}
+
+// lookup returns the cached value of key, or else the generation of the
+// cache of user to store the value with.
+func (s *cachingService) lookup(user, key string) (interface{}, uint64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.users[user]
+	if c == nil {
+		return nil, 0, false
+	}
+	e, ok := c.entries[key]
+	if !ok || time.Now().After(e.expires) {
+		return nil, c.generation, false
+	}
+
+	return e.value, c.generation, true
+}
+
+// store caches v, unless the documents of user changed since generation.
+func (s *cachingService) store(user, key string, generation uint64, v interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.users[user]
+	if c == nil {
+		c = &userCache{}
+		s.users[user] = c
+	}
+	if c.generation != generation {
+		return
+	}
+	if c.entries == nil {
+		c.entries = map[string]cacheEntry{}
+	}
+	c.entries[key] = cacheEntry{value: v, expires: time.Now().Add(s.ttl)}
+}
+
+func (s *cachingService) invalidate(user string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.users[user]
+	if c == nil {
+		c = &userCache{}
+		s.users[user] = c
+	}
+	c.generation++
+	c.entries = nil
+}
+
+func (s *cachingService) IndexContext(ctx context.Context, text, lang, user string, opts ...IndexOption) (*IndexResult, error) {
+	defer s.invalidate(user)
+	return s.next.IndexContext(ctx, text, lang, user, opts...)
+}
+
+func (s *cachingService) SearchPage(ctx context.Context, query, user string, page Page) (*SearchResult, error) {
+	key := fmt.Sprintf("search\x00%s\x00%d\x00%d", query, page.Limit, page.Offset)
+	v, generation, ok := s.lookup(user, key)
+	if ok {
+		return copySearchResult(v.(*SearchResult)), nil
+	}
+
+	res, err := s.next.SearchPage(ctx, query, user, page)
+	if err == nil {
+		s.store(user, key, generation, copySearchResult(res))
+	}
+
+	return res, err
+}
+
+func (s *cachingService) GetContext(ctx context.Context, id, user string) (*Document, error) {
+	key := "get\x00" + id
+	v, generation, ok := s.lookup(user, key)
+	if ok {
+		return copyDocument(v.(*Document)), nil
+	}
+
+	res, err := s.next.GetContext(ctx, id, user)
+	if err == nil {
+		s.store(user, key, generation, copyDocument(res))
+	}
+
+	return res, err
+}
+
+func (s *cachingService) UpdateContext(ctx context.Context, id, text, lang, user string) (*IndexResult, error) {
+	defer s.invalidate(user)
+	return s.next.UpdateContext(ctx, id, text, lang, user)
+}
+
+func (s *cachingService) DeleteContext(ctx context.Context, id, user string) error {
+	defer s.invalidate(user)
+	return s.next.DeleteContext(ctx, id, user)
+}
+
+func copySearchResult(r *SearchResult) *SearchResult {
+	c := *r
+	if r.Documents != nil {
+		c.Documents = make([]Document, len(r.Documents))
+		for i := range r.Documents {
+			c.Documents[i] = *copyDocument(&r.Documents[i])
+		}
+	}
+
+	return &c
+}
+
+func copyDocument(d *Document) *Document {
+	c := *d
+	c.Entities = copyEntities(d.Entities)
+	if d.Metadata != nil {
+		c.Metadata = make(map[string]string, len(d.Metadata))
+		for k, v := range d.Metadata {
+			c.Metadata[k] = v
+		}
+	}
+
+	return &c
+}
+
+func copyEntities(entities []Entity) []Entity {
+	if entities == nil {
+		return nil
+	}
+	c := make([]Entity, len(entities))
+	for i, e := range entities {
+		c[i] = e
+		c[i].Classifications = append([]string(nil), e.Classifications...)
+	}
+
+	return c
+}
+
+// NewRateLimitedService spaces calls to next so that at most perSecond
+// of them start every second. Calls wait for their turn unless their
+// context is done first.
+func NewRateLimitedService(next Service, perSecond float64) Service {
+	return &rateLimitedService{
+		next:     next,
+		interval: time.Duration(float64(time.Second) / perSecond),
+	}
+}
+
+type rateLimitedService struct {
+	next     Service
+	interval time.Duration
+
+	mu   sync.Mutex
+	slot time.Time
+}
+
+// wait blocks until the next free call slot.
+func (s *rateLimitedService) wait(ctx context.Context) error {
+	s.mu.Lock()
+	now := time.Now()
+	if s.slot.Before(now) {
+		s.slot = now
+	}
+	at := s.slot
+	s.slot = s.slot.Add(s.interval)
+	s.mu.Unlock()
+
+	return sleepCtx(ctx, time.Until(at))
+}
+
+func (s *rateLimitedService) IndexContext(ctx context.Context, text, lang, user string, opts ...IndexOption) (*IndexResult, error) {
+	if err := s.wait(ctx); err != nil {
+		return nil, err
+	}
+	return s.next.IndexContext(ctx, text, lang, user, opts...)
+}
+
+func (s *rateLimitedService) SearchPage(ctx context.Context, query, user string, page Page) (*SearchResult, error) {
+	if err := s.wait(ctx); err != nil {
+		return nil, err
+	}
+	return s.next.SearchPage(ctx, query, user, page)
+}
+
+func (s *rateLimitedService) GetContext(ctx context.Context, id, user string) (*Document, error) {
+	if err := s.wait(ctx); err != nil {
+		return nil, err
+	}
+	return s.next.GetContext(ctx, id, user)
+}
+
+func (s *rateLimitedService) UpdateContext(ctx context.Context, id, text, lang, user string) (*IndexResult, error) {
+	if err := s.wait(ctx); err != nil {
+		return nil, err
+	}
+	return s.next.UpdateContext(ctx, id, text, lang, user)
+}
+
+func (s *rateLimitedService) DeleteContext(ctx context.Context, id, user string) error {
+	if err := s.wait(ctx); err != nil {
+		return err
+	}
+	return s.next.DeleteContext(ctx, id, user)
+}
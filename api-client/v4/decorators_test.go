@@ -0,0 +1,230 @@
+package v4_test
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	v4 "github.com/repustate/rcli/api-client/v4"
+	"github.com/repustate/rcli/api-client/v4/v4test"
+)
+
+// countingService counts the searches and gets reaching the server.
+type countingService struct {
+	v4.Service
+	searches, gets int32
+	// searching, when set, receives every search before it is sent and
+	// holds it until release is closed
+	searching chan struct{}
+	release   chan struct{}
+}
+
+func (s *countingService) SearchPage(ctx context.Context, query, user string, page v4.Page) (*v4.SearchResult, error) {
+	atomic.AddInt32(&s.searches, 1)
+	res, err := s.Service.SearchPage(ctx, query, user, page)
+	if s.searching != nil {
+		s.searching <- struct{}{}
+		<-s.release
+	}
+	return res, err
+}
+
+func (s *countingService) GetContext(ctx context.Context, id, user string) (*v4.Document, error) {
+	atomic.AddInt32(&s.gets, 1)
+	return s.Service.GetContext(ctx, id, user)
+}
+
+func newTestService(t *testing.T) (*countingService, *httptest.Server) {
+	srv := v4test.NewServer()
+	t.Cleanup(srv.Close)
+	c, err := v4.New(v4.WithServerURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &countingService{Service: &c}, srv
+}
+
+func TestCachingServiceCopies(t *testing.T) {
+	backend, _ := newTestService(t)
+	svc := v4.NewCachingService(backend, time.Minute)
+	ctx := context.Background()
+
+	res, err := svc.IndexContext(ctx, "The weather in London is good.", "en", "ann", v4.WithMetadata(map[string]string{"source": "a.txt"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := svc.GetContext(ctx, res.ID, "ann")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc.Text = "changed"
+	doc.Metadata["source"] = "changed"
+	if len(doc.Entities) != 0 {
+		doc.Entities[0].Title = "changed"
+	}
+
+	cached, err := svc.GetContext(ctx, res.ID, "ann")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached.Text != "The weather in London is good." || cached.Metadata["source"] != "a.txt" {
+		t.Errorf("GetContext() = %+v, the cached document was changed by its caller", cached)
+	}
+	if len(cached.Entities) != 0 && cached.Entities[0].Title == "changed" {
+		t.Error("the cached entities were changed by the caller")
+	}
+	if backend.gets != 1 {
+		t.Errorf("%d gets reached the server, want 1", backend.gets)
+	}
+
+	found, err := svc.SearchPage(ctx, "theme:weather", "ann", v4.Page{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found.Documents) != 1 {
+		t.Fatalf("SearchPage() = %+v, want 1 match", found)
+	}
+	found.Documents[0].Text = "changed"
+	found.Documents = nil
+
+	found, err = svc.SearchPage(ctx, "theme:weather", "ann", v4.Page{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found.Documents) != 1 || found.Documents[0].Text != "The weather in London is good." {
+		t.Errorf("SearchPage() = %+v, the cached result was changed by its caller", found)
+	}
+	if backend.searches != 1 {
+		t.Errorf("%d searches reached the server, want 1", backend.searches)
+	}
+}
+
+func TestCachingServiceInvalidate(t *testing.T) {
+	backend, _ := newTestService(t)
+	svc := v4.NewCachingService(backend, time.Minute)
+	ctx := context.Background()
+
+	search := func(user string) int {
+		res, err := svc.SearchPage(ctx, "theme:weather", user, v4.Page{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.Total
+	}
+
+	svc.IndexContext(ctx, "The weather is good.", "en", "ann")
+	if n := search("ann"); n != 1 {
+		t.Fatalf("%d matches, want 1", n)
+	}
+	search("bob")
+
+	// a new document drops the results of its user only
+	res, _ := svc.IndexContext(ctx, "The weather is bad.", "en", "ann")
+	if n := search("ann"); n != 2 {
+		t.Errorf("%d matches after indexing, want 2", n)
+	}
+	search("bob")
+	if backend.searches != 3 {
+		t.Errorf("%d searches reached the server, want 3", backend.searches)
+	}
+
+	svc.DeleteContext(ctx, res.ID, "ann")
+	if n := search("ann"); n != 1 {
+		t.Errorf("%d matches after deleting, want 1", n)
+	}
+}
+
+func TestCachingServiceStaleStore(t *testing.T) {
+	backend, _ := newTestService(t)
+	backend.searching = make(chan struct{})
+	backend.release = make(chan struct{})
+	svc := v4.NewCachingService(backend, time.Minute)
+	ctx := context.Background()
+
+	// a search answered before a document is indexed, but returning after
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		svc.SearchPage(ctx, "theme:weather", "ann", v4.Page{})
+	}()
+	<-backend.searching
+	if _, err := svc.IndexContext(ctx, "The weather is good.", "en", "ann"); err != nil {
+		t.Fatal(err)
+	}
+	close(backend.release)
+	<-done
+
+	backend.searching = nil
+	res, err := svc.SearchPage(ctx, "theme:weather", "ann", v4.Page{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Total != 1 {
+		t.Errorf("SearchPage() found %d documents, the stale result was cached", res.Total)
+	}
+}
+
+func TestCachingServiceExpiry(t *testing.T) {
+	backend, _ := newTestService(t)
+	svc := v4.NewCachingService(backend, time.Millisecond)
+	ctx := context.Background()
+
+	svc.SearchPage(ctx, "theme:weather", "ann", v4.Page{})
+	time.Sleep(5 * time.Millisecond)
+	svc.SearchPage(ctx, "theme:weather", "ann", v4.Page{})
+	if backend.searches != 2 {
+		t.Errorf("%d searches reached the server, want 2", backend.searches)
+	}
+}
+
+func TestRateLimitedService(t *testing.T) {
+	backend, _ := newTestService(t)
+	svc := v4.NewRateLimitedService(backend, 100)
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if _, err := svc.SearchPage(ctx, "theme:weather", "ann", v4.Page{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := time.Since(start); d < 40*time.Millisecond {
+		t.Errorf("5 calls at 100 per second took %v", d)
+	}
+
+	slow := v4.NewRateLimitedService(backend, 0.1)
+	slow.SearchPage(ctx, "theme:weather", "ann", v4.Page{})
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := slow.SearchPage(ctx, "theme:weather", "ann", v4.Page{}); err != context.DeadlineExceeded {
+		t.Errorf("SearchPage() error = %v, want the context deadline", err)
+	}
+}
+
+func TestLoggingService(t *testing.T) {
+	backend, _ := newTestService(t)
+	var buf bytes.Buffer
+	svc := v4.NewLoggingService(backend, log.New(&buf, "", 0))
+	ctx := context.Background()
+
+	svc.IndexContext(ctx, "The weather in London is good and the streets are full of people.", "en", "ann", v4.WithDocumentID("doc-1"))
+	svc.GetContext(ctx, "missing", "ann")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("logged %q, want 2 lines", lines)
+	}
+	if want := `index text="The weather in London is good and the st..." lang="en" id="doc-1" succeeded in `; !strings.HasPrefix(lines[0], want) {
+		t.Errorf("logged %q, want %q", lines[0], want)
+	}
+	if want := `get id="missing" failed after `; !strings.HasPrefix(lines[1], want) || !strings.Contains(lines[1], "404") {
+		t.Errorf("logged %q, want %q and the 404 error", lines[1], want)
+	}
+}
@@ -0,0 +1,122 @@
+package v4
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// maximum length of a raw response body quoted in an error message
+	maxErrBodyLen = 200
+)
+
+// APIError is returned when the server responds with a non-2xx status.
+type APIError struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// Status is the HTTP status line text, e.g. "400 Bad Request".
+	Status string
+	// Method and Endpoint identify the failed request, e.g. "GET" and "/demo/search".
+	Method   string
+	Endpoint string
+	// RequestID is the server-assigned request identifier, if any.
+	RequestID string
+	// Message is the error message decoded from the response body.
+	Message string
+	// Body is the raw response body.
+	Body []byte
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("%s %s: server responded %q", e.Method, e.Endpoint, e.Status)
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.RequestID != "" {
+		msg += fmt.Sprintf(" (request id %s)", e.RequestID)
+	}
+
+	return msg
+}
+
+// IsRateLimited reports whether err is an APIError for a 429 response.
+func IsRateLimited(err error) bool {
+	return hasStatus(err, http.StatusTooManyRequests)
+}
+
+// IsNotFound reports whether err is an APIError for a 404 response.
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsBadRequest reports whether err is an APIError for a 400 or 422 response,
+// i.e. the server rejected the request content.
+func IsBadRequest(err error) bool {
+	return hasStatus(err, http.StatusBadRequest, http.StatusUnprocessableEntity)
+}
+
+func hasStatus(err error, codes ...int) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	for _, code := range codes {
+		if apiErr.StatusCode == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+func newAPIError(req *http.Request, resp *http.Response, body []byte) *APIError {
+	return &APIError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Method:     req.Method,
+		Endpoint:   req.URL.Path,
+		RequestID:  requestID(resp.Header),
+		Message:    errorMessage(body),
+		Body:       body,
+	}
+}
+
+func requestID(h http.Header) string {
+	for _, key := range []string{"X-Request-Id", "Request-Id", "X-Correlation-Id"} {
+		if id := h.Get(key); id != "" {
+			return id
+		}
+	}
+
+	return ""
+}
+
+// errorMessage decodes the error description from a response body,
+// e.g. {"error": "unsupported language"}. Bodies which are not JSON
+// are returned as is, truncated.
+func errorMessage(body []byte) string {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(body, &doc); err == nil {
+		for _, key := range []string{"error", "message", "detail", "msg"} {
+			switch v := doc[key].(type) {
+			case string:
+				return v
+			case map[string]interface{}:
+				if msg, ok := v["message"].(string); ok {
+					return msg
+				}
+			}
+		}
+	}
+
+	msg := []rune(strings.TrimSpace(string(body)))
+	if len(msg) > maxErrBodyLen {
+		return string(msg[:maxErrBodyLen]) + "..."
+	}
+
+	return string(msg)
+}
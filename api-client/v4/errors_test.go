@@ -0,0 +1,61 @@
+package v4
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestErrorMessage(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{`{"error": "unsupported language"}`, "unsupported language"},
+		{`{"message": "quota exceeded"}`, "quota exceeded"},
+		{`{"detail": "not found"}`, "not found"},
+		{`{"error": {"code": 3, "message": "bad query"}}`, "bad query"},
+		{`{"status": "failed"}`, `{"status": "failed"}`},
+		{"  upstream timeout\n", "upstream timeout"},
+		{"", ""},
+		{strings.Repeat("é", 300), strings.Repeat("é", maxErrBodyLen) + "..."},
+	}
+
+	for _, tt := range tests {
+		if got := errorMessage([]byte(tt.body)); got != tt.want {
+			t.Errorf("errorMessage(%q) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "req-42")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error": "slow down"}`))
+	}))
+	defer srv.Close()
+
+	c, err := New(WithServerURL(srv.URL), WithRetryPolicy(RetryPolicy{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.Search("theme:weather", "ann")
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Search() error = %v, want an APIError", err)
+	}
+	if want := `GET /demo/search: server responded "429 Too Many Requests": slow down (request id req-42)`; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+	if !IsRateLimited(err) || IsNotFound(err) || IsBadRequest(err) {
+		t.Errorf("the status helpers disagree on %v", err)
+	}
+	if IsRateLimited(errors.New("429")) {
+		t.Error("IsRateLimited() of an error which is not an APIError")
+	}
+}
@@ -0,0 +1,105 @@
+package v4
+
+import (
+	"context"
+)
+
+const (
+	// DefaultPageSize is the number of matches a SearchIterator fetches
+	// at once when no page size is given.
+	DefaultPageSize = 100
+)
+
+// Page selects a window of search matches. Zero Limit leaves
+// the number of matches up to the server.
+type Page struct {
+	Limit  int
+	Offset int
+}
+
+// SearchIterator walks all matches of a query, fetching them
+// page by page as they are consumed:
+//
+//	it := NewSearchIterator(s, query, user, 50)
+//	for it.Next(ctx) {
+//		doc := it.Document()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type SearchIterator struct {
+	s        Searcher
+	query    string
+	user     string
+	pageSize int
+
+	offset int
+	total  int
+	buf    []Document
+	cur    Document
+	done   bool
+	err    error
+}
+
+// NewSearchIterator creates an iterator over all matches of query,
+// fetching pageSize matches per request from s.
+func NewSearchIterator(s Searcher, query, user string, pageSize int) *SearchIterator {
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+
+	return &SearchIterator{
+		s:        s,
+		query:    query,
+		user:     user,
+		pageSize: pageSize,
+	}
+}
+
+// Next advances to the next match, fetching the next page when needed.
+// It returns false when there are no more matches or a request failed.
+func (it *SearchIterator) Next(ctx context.Context) bool {
+	if len(it.buf) == 0 && !it.done {
+		it.fetch(ctx)
+	}
+	if len(it.buf) == 0 {
+		return false
+	}
+
+	it.cur, it.buf = it.buf[0], it.buf[1:]
+	return true
+}
+
+func (it *SearchIterator) fetch(ctx context.Context) {
+	res, err := it.s.SearchPage(ctx, it.query, it.user, Page{Limit: it.pageSize, Offset: it.offset})
+	if err != nil {
+		it.err = err
+		it.done = true
+		return
+	}
+
+	it.total = res.Total
+	it.buf = res.Documents
+	it.offset += len(res.Documents)
+	// servers ignoring the limit return all matches at once
+	if len(res.Documents) == 0 || it.offset >= res.Total {
+		it.done = true
+	}
+}
+
+// Document returns the current match.
+func (it *SearchIterator) Document() Document {
+	return it.cur
+}
+
+// Total returns the number of all matches, known once the first page
+// was fetched.
+func (it *SearchIterator) Total() int {
+	return it.total
+}
+
+// Err returns the error which stopped the iteration, if any.
+func (it *SearchIterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,84 @@
+package v4
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// pagedSearcher serves total matches, ignoring the limit when all is set
+// and failing from the request numbered failAt on.
+type pagedSearcher struct {
+	total  int
+	all    bool
+	failAt int
+	pages  []Page
+}
+
+func (s *pagedSearcher) SearchPage(ctx context.Context, query, user string, page Page) (*SearchResult, error) {
+	s.pages = append(s.pages, page)
+	if s.failAt > 0 && len(s.pages) >= s.failAt {
+		return nil, errors.New("server down")
+	}
+
+	end := page.Offset + page.Limit
+	if s.all || end > s.total {
+		end = s.total
+	}
+	res := &SearchResult{Total: s.total}
+	for i := page.Offset; i < end; i++ {
+		res.Documents = append(res.Documents, Document{ID: fmt.Sprintf("doc-%d", i)})
+	}
+
+	return res, nil
+}
+
+func TestSearchIterator(t *testing.T) {
+	tests := []struct {
+		name      string
+		s         *pagedSearcher
+		pageSize  int
+		wantDocs  int
+		wantPages []Page
+		wantErr   bool
+	}{
+		{"no matches", &pagedSearcher{}, 10, 0, []Page{{10, 0}}, false},
+		{"one page", &pagedSearcher{total: 7}, 10, 7, []Page{{10, 0}}, false},
+		{"full pages", &pagedSearcher{total: 20}, 10, 20, []Page{{10, 0}, {10, 10}}, false},
+		{"last page short", &pagedSearcher{total: 25}, 10, 25, []Page{{10, 0}, {10, 10}, {10, 20}}, false},
+		{"server ignores the limit", &pagedSearcher{total: 25, all: true}, 10, 25, []Page{{10, 0}}, false},
+		{"default page size", &pagedSearcher{total: 150}, 0, 150, []Page{{100, 0}, {100, 100}}, false},
+		{"failing page", &pagedSearcher{total: 25, failAt: 2}, 10, 10, []Page{{10, 0}, {10, 10}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			it := NewSearchIterator(tt.s, "theme:weather", "ann", tt.pageSize)
+			n := 0
+			for it.Next(context.Background()) {
+				if want := fmt.Sprintf("doc-%d", n); it.Document().ID != want {
+					t.Errorf("match %d is %s, want %s", n, it.Document().ID, want)
+				}
+				n++
+			}
+			// a finished iterator stays finished
+			if it.Next(context.Background()) {
+				t.Error("Next() after the end returned true")
+			}
+
+			if n != tt.wantDocs {
+				t.Errorf("%d matches, want %d", n, tt.wantDocs)
+			}
+			if (it.Err() != nil) != tt.wantErr {
+				t.Errorf("Err() = %v, want error %v", it.Err(), tt.wantErr)
+			}
+			if tt.s.failAt == 0 && it.Total() != tt.s.total {
+				t.Errorf("Total() = %d, want %d", it.Total(), tt.s.total)
+			}
+			if fmt.Sprint(tt.s.pages) != fmt.Sprint(tt.wantPages) {
+				t.Errorf("requested pages %v, want %v", tt.s.pages, tt.wantPages)
+			}
+		})
+	}
+}
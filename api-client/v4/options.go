@@ -0,0 +1,129 @@
+package v4
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultServerURL is the address of the public Repustate demo server.
+	DefaultServerURL = "http://try.repustate.com:9000"
+	// DefaultBasePath is the API path prefix used by the demo server.
+	DefaultBasePath = "demo"
+)
+
+// Option configures a Client created with New.
+type Option func(*Client)
+
+// WithServerURL sets the address of the Repustate server,
+// e.g. "https://repustate.example.com:9000".
+func WithServerURL(u string) Option {
+	return func(c *Client) {
+		c.serverURL = u
+	}
+}
+
+// WithBasePath sets the path prefix all API endpoints are resolved against.
+func WithBasePath(p string) Option {
+	return func(c *Client) {
+		c.basePath = strings.Trim(p, "/")
+	}
+}
+
+// WithAPIKey sets the key sent in the X-Api-Key header of every request.
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithBearerToken sets the token sent in the Authorization header of every
+// request. It takes precedence over an API key.
+func WithBearerToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithRequestLog logs every request attempt and its response status,
+// with credentials redacted.
+func WithRequestLog(l *log.Logger) Option {
+	return func(c *Client) {
+		c.reqLog = l
+	}
+}
+
+// WithLanguage sets the language used for indexing when none is given.
+func WithLanguage(lang string) Option {
+	return func(c *Client) {
+		c.lang = lang
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to send requests,
+// http.DefaultClient is used by default.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithTimeout limits the duration of every single request attempt,
+// zero means no limit.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = d
+	}
+}
+
+// WithRetryPolicy sets how failed requests are retried.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = p
+	}
+}
+
+// IndexOptions are the optional properties of a document being indexed.
+type IndexOptions struct {
+	// ID is the id to index the document under, chosen by the server
+	// when empty.
+	ID string
+	// Metadata is carried along with the document and returned with it.
+	Metadata map[string]string
+}
+
+// IndexOption sets an optional property of a document being indexed.
+type IndexOption func(*IndexOptions)
+
+// WithDocumentID indexes the document under id, replacing the document
+// indexed under the same id before, if any.
+func WithDocumentID(id string) IndexOption {
+	return func(o *IndexOptions) {
+		o.ID = id
+	}
+}
+
+// WithMetadata attaches fields to the document, e.g. its author or the
+// file it was read from. The fields are merged with the ones set before.
+func WithMetadata(md map[string]string) IndexOption {
+	return func(o *IndexOptions) {
+		if len(md) != 0 && o.Metadata == nil {
+			o.Metadata = make(map[string]string, len(md))
+		}
+		for k, v := range md {
+			o.Metadata[k] = v
+		}
+	}
+}
+
+// NewIndexOptions returns the properties set by opts.
+func NewIndexOptions(opts ...IndexOption) IndexOptions {
+	var o IndexOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
@@ -0,0 +1,117 @@
+package v4
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// RetryPolicy controls how requests which timed out, whose connection was
+// refused, reset or closed early, or which failed with 429 Too Many
+// Requests or a 5xx status are retried. Requests indexing a
+// document without an id of its own are not idempotent, a retry could
+// index the document twice: they are only retried after a 429 response,
+// or a 503 response with a Retry-After header, which the server sends
+// for requests it did not handle.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt,
+	// zero disables retrying.
+	MaxRetries int
+	// MinBackoff is the delay before the first retry, doubled on each
+	// following one.
+	MinBackoff time.Duration
+	// MaxBackoff caps the delay between two attempts.
+	MaxBackoff time.Duration
+}
+
+var (
+	// DefaultRetryPolicy is used by clients created without WithRetryPolicy.
+	DefaultRetryPolicy = RetryPolicy{
+		MaxRetries: 3,
+		MinBackoff: 500 * time.Millisecond,
+		MaxBackoff: 10 * time.Second,
+	}
+)
+
+// backoff returns the delay before the given retry (counting from zero),
+// with up to 20% of random jitter.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	d := p.MinBackoff
+	for i := 0; i < retry && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+
+	return d - time.Duration(rand.Int63n(int64(d)/5+1))
+}
+
+// retryAfter parses the Retry-After header in its delay-seconds form.
+func retryAfter(resp *http.Response) time.Duration {
+	secs, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || secs < 0 {
+		return 0
+	}
+
+	return time.Duration(secs) * time.Second
+}
+
+// unhandled reports whether resp tells the server did not handle the
+// request, so that it may be sent again even if it is not idempotent.
+func unhandled(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusServiceUnavailable:
+		return resp.Header.Get("Retry-After") != ""
+	}
+
+	return false
+}
+
+func retryableStatus(code int) bool {
+	if code == http.StatusNotImplemented {
+		return false
+	}
+	return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+}
+
+// retryableErr reports whether a request failing with err may succeed
+// when sent again: it timed out, its connection was refused, reset or
+// closed early. Other errors, e.g. bad certificates or unsupported
+// schemes, would fail every retry.
+func retryableErr(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// sleepCtx waits for d unless ctx is done first.
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
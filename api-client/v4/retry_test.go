@@ -0,0 +1,218 @@
+package v4
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// failingServer answers the first failures requests with status and
+// header, and the following ones with body.
+func failingServer(t *testing.T, failures int32, status int, header map[string]string, body string) (*httptest.Server, *int32) {
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) <= failures {
+			for k, v := range header {
+				w.Header().Set(k, v)
+			}
+			w.WriteHeader(status)
+			w.Write([]byte(`{"message": "try again"}`))
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, &attempts
+}
+
+func newTestClient(t *testing.T, srv *httptest.Server) Client {
+	c, err := New(WithServerURL(srv.URL), WithRetryPolicy(RetryPolicy{MaxRetries: 2, MinBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+func TestRetry(t *testing.T) {
+	const indexed = `{"id": "doc-1", "themes": [], "sentiment": "neu"}`
+	retryAfter := map[string]string{"Retry-After": "0"}
+
+	tests := []struct {
+		name         string
+		failures     int32
+		status       int
+		header       map[string]string
+		ownID        bool
+		wantErr      bool
+		wantAttempts int32
+	}{
+		{"index retried after 429", 1, http.StatusTooManyRequests, nil, false, false, 2},
+		{"index retried after 503 with Retry-After", 2, http.StatusServiceUnavailable, retryAfter, false, false, 3},
+		{"index not retried after 503", 1, http.StatusServiceUnavailable, nil, false, true, 1},
+		{"index not retried after 500", 1, http.StatusInternalServerError, nil, false, true, 1},
+		{"index with an id retried after 500", 1, http.StatusInternalServerError, nil, true, false, 2},
+		{"retries run out", 3, http.StatusTooManyRequests, nil, false, true, 3},
+		{"bad request not retried", 1, http.StatusBadRequest, nil, true, true, 1},
+		{"not implemented not retried", 1, http.StatusNotImplemented, nil, true, true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, attempts := failingServer(t, tt.failures, tt.status, tt.header, indexed)
+			c := newTestClient(t, srv)
+
+			var opts []IndexOption
+			if tt.ownID {
+				opts = append(opts, WithDocumentID("doc-1"))
+			}
+			_, err := c.IndexContext(context.Background(), "The weather is good.", "en", "ann", opts...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("IndexContext() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got := atomic.LoadInt32(attempts); got != tt.wantAttempts {
+				t.Errorf("%d attempts, want %d", got, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestRetryIdempotent(t *testing.T) {
+	srv, attempts := failingServer(t, 2, http.StatusBadGateway, nil, `{"id": "doc-1", "text": "Sunny."}`)
+	c := newTestClient(t, srv)
+
+	doc, err := c.GetContext(context.Background(), "doc-1", "ann")
+	if err != nil {
+		t.Fatalf("GetContext() error = %v", err)
+	}
+	if doc.ID != "doc-1" || atomic.LoadInt32(attempts) != 3 {
+		t.Errorf("GetContext() = %+v after %d attempts", doc, atomic.LoadInt32(attempts))
+	}
+}
+
+func TestRetryConnectionError(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	c := newTestClient(t, srv)
+	srv.Close()
+
+	start := time.Now()
+	if _, err := c.IndexContext(context.Background(), "Text.", "en", "ann"); err == nil {
+		t.Fatal("IndexContext() succeeded against a closed server")
+	}
+	if _, err := c.GetContext(context.Background(), "doc-1", "ann"); err == nil {
+		t.Fatal("GetContext() succeeded against a closed server")
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("retries took too long")
+	}
+}
+
+func TestRetryableErr(t *testing.T) {
+	dial := func(err error) error {
+		return &url.Error{Op: "Get", URL: "http://localhost", Err: &net.OpError{Op: "dial", Net: "tcp", Err: err}}
+	}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"connection refused", dial(os.NewSyscallError("connect", syscall.ECONNREFUSED)), true},
+		{"connection reset", dial(os.NewSyscallError("read", syscall.ECONNRESET)), true},
+		{"timeout", dial(&net.DNSError{Err: "i/o timeout", IsTimeout: true}), true},
+		{"deadline", &url.Error{Op: "Get", URL: "http://localhost", Err: context.DeadlineExceeded}, true},
+		{"closed early", &url.Error{Op: "Get", URL: "http://localhost", Err: io.ErrUnexpectedEOF}, true},
+		{"canceled", &url.Error{Op: "Get", URL: "http://localhost", Err: context.Canceled}, false},
+		{"unknown host", dial(&net.DNSError{Err: "no such host", Name: "nowhere.invalid", IsNotFound: true}), false},
+		{"bad certificate", &url.Error{Op: "Get", URL: "https://localhost", Err: x509.UnknownAuthorityError{}}, false},
+	}
+
+	for _, tt := range tests {
+		if got := retryableErr(tt.err); got != tt.want {
+			t.Errorf("retryableErr() of %s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRetryPermanentErr(t *testing.T) {
+	var conns int32
+	srv := httptest.NewUnstartedServer(http.NotFoundHandler())
+	srv.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	srv.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	// the client does not trust the certificate of the server
+	c := newTestClient(t, srv)
+	if _, err := c.GetContext(context.Background(), "doc-1", "ann"); err == nil {
+		t.Fatal("GetContext() succeeded with an untrusted certificate")
+	}
+	if n := atomic.LoadInt32(&conns); n != 1 {
+		t.Errorf("GetContext() with an untrusted certificate made %d attempts, want 1", n)
+	}
+
+	c, err := New(WithServerURL("ftp://localhost"), WithRetryPolicy(RetryPolicy{MaxRetries: 2, MinBackoff: time.Hour}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := c.GetContext(ctx, "doc-1", "ann"); err == nil || errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetContext() with an unsupported scheme error = %v, want no retries", err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	p := RetryPolicy{MinBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	tests := []struct {
+		retry    int
+		min, max time.Duration
+	}{
+		{0, 80 * time.Millisecond, 100 * time.Millisecond},
+		{1, 160 * time.Millisecond, 200 * time.Millisecond},
+		{3, 640 * time.Millisecond, 800 * time.Millisecond},
+		{10, 800 * time.Millisecond, time.Second},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if d := p.backoff(tt.retry); d < tt.min || d > tt.max {
+				t.Errorf("backoff(%d) = %v, want between %v and %v", tt.retry, d, tt.min, tt.max)
+			}
+		}
+	}
+	if d := (RetryPolicy{}).backoff(2); d != 0 {
+		t.Errorf("backoff without MinBackoff = %v, want 0", d)
+	}
+}
+
+func TestRetryAfterCancel(t *testing.T) {
+	srv, _ := failingServer(t, 1, http.StatusTooManyRequests, map[string]string{"Retry-After": "60"}, "{}")
+	c := newTestClient(t, srv)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := c.IndexContext(ctx, "Text.", "en", "ann"); err == nil {
+		t.Fatal("IndexContext() succeeded")
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("the retry did not stop with the context")
+	}
+}
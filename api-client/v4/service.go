@@ -0,0 +1,32 @@
+package v4
+
+import (
+	"context"
+)
+
+// Indexer adds documents to a user's index.
+type Indexer interface {
+	IndexContext(ctx context.Context, text, lang, user string, opts ...IndexOption) (*IndexResult, error)
+}
+
+// Searcher runs queries against a user's index.
+type Searcher interface {
+	SearchPage(ctx context.Context, query, user string, page Page) (*SearchResult, error)
+}
+
+// DocumentStore manages indexed documents by their id.
+type DocumentStore interface {
+	GetContext(ctx context.Context, id, user string) (*Document, error)
+	UpdateContext(ctx context.Context, id, text, lang, user string) (*IndexResult, error)
+	DeleteContext(ctx context.Context, id, user string) error
+}
+
+// Service is the complete Repustate API, implemented by Client and
+// by the decorators wrapping it.
+type Service interface {
+	Indexer
+	Searcher
+	DocumentStore
+}
+
+var _ Service = (*Client)(nil)
@@ -1,22 +1,25 @@
 package v4
 
 type IndexResult struct {
-	Themes    []string `json:"themes"`
-	Sentiment string   `json:"sentiment"`
-	Entities  []Entity `json:"entities"`
+	ID        string   `json:"id" yaml:"id"`
+	Themes    []string `json:"themes" yaml:"themes"`
+	Sentiment string   `json:"sentiment" yaml:"sentiment"`
+	Entities  []Entity `json:"entities" yaml:"entities"`
 }
 
 type SearchResult struct {
-	Total     int        `json:"total"`
-	Documents []Document `json:"matches"`
+	Total     int        `json:"total" yaml:"total"`
+	Documents []Document `json:"matches" yaml:"matches"`
 }
 
 type Document struct {
-	Text     string   `json:"text"`
-	Entities []Entity `json:"entities"`
+	ID       string            `json:"id" yaml:"id"`
+	Text     string            `json:"text" yaml:"text"`
+	Entities []Entity          `json:"entities" yaml:"entities"`
+	Metadata map[string]string `json:"metadata,omitempty" yaml:"metadata,omitempty"`
 }
 
 type Entity struct {
-	Title           string   `json:"title"`
-	Classifications []string `json:"classifications"`
+	Title           string   `json:"title" yaml:"title"`
+	Classifications []string `json:"classifications" yaml:"classifications"`
 }
@@ -0,0 +1,149 @@
+package v4test
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	v4 "github.com/repustate/rcli/api-client/v4"
+)
+
+var (
+	// words hinting at a theme, matched against lower-cased words of the text
+	themeWords = map[string][]string{
+		"business":       {"business", "company", "companies", "startup", "ceo", "customer", "customers", "sales"},
+		"education":      {"school", "university", "student", "students", "teacher", "education"},
+		"entertainment":  {"movie", "film", "actor", "actress", "show", "tv", "celebrity"},
+		"finance":        {"bank", "banks", "money", "stock", "stocks", "market", "markets", "economy", "invest", "investment", "tax"},
+		"food":           {"food", "pizza", "restaurant", "bread", "cheese", "dinner", "lunch", "breakfast", "cake"},
+		"health":         {"health", "doctor", "hospital", "virus", "vaccine", "medicine", "disease", "patient"},
+		"music":          {"music", "song", "songs", "album", "concert", "band", "singer"},
+		"politics":       {"election", "government", "minister", "president", "parliament", "politics", "vote", "chancellor"},
+		"science":        {"science", "research", "scientist", "scientists", "experiment", "physics", "biology"},
+		"space":          {"space", "rocket", "nasa", "planet", "orbit", "astronaut", "moon"},
+		"sports":         {"sports", "sport", "football", "soccer", "hockey", "tennis", "goal", "team", "match", "game"},
+		"technology":     {"technology", "computer", "software", "internet", "phone", "app", "ai", "robot"},
+		"transportation": {"car", "cars", "train", "bus", "flight", "airport", "traffic"},
+		"weather":        {"weather", "rain", "sunny", "snow", "storm", "cold", "hot", "wind"},
+	}
+
+	positiveWords = wordSet("good", "great", "love", "like", "excellent", "amazing", "happy", "best",
+		"wonderful", "nice", "beautiful", "win", "won", "enjoy", "awesome")
+	negativeWords = wordSet("bad", "terrible", "hate", "awful", "worst", "sad", "poor", "lose", "lost",
+		"horrible", "angry", "crash", "war", "disappointing", "ugly")
+
+	// known entities and their classifications, matched case-sensitively
+	gazetteer = map[string][]string{
+		"Angela Merkel":   {"Person.politician"},
+		"Barack Obama":    {"Person.politician"},
+		"Emmanuel Macron": {"Person.politician"},
+		"Lionel Messi":    {"Person.athlete"},
+		"Berlin":          {"Location.city"},
+		"London":          {"Location.city"},
+		"Madrid":          {"Location.city"},
+		"Moscow":          {"Location.city"},
+		"New York":        {"Location.city"},
+		"Paris":           {"Location.city"},
+		"Toronto":         {"Location.city"},
+		"Canada":          {"Location.country"},
+		"China":           {"Location.country"},
+		"France":          {"Location.country"},
+		"Germany":         {"Location.country"},
+		"Russia":          {"Location.country"},
+		"Spain":           {"Location.country"},
+		"Europe":          {"Location.continent"},
+		"Apple":           {"Org.business"},
+		"Google":          {"Org.business"},
+		"Repustate":       {"Org.business"},
+		"NASA":            {"Org.government"},
+		"Monday":          {"Time.day"},
+		"January":         {"Time.month"},
+	}
+)
+
+func wordSet(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[w] = true
+	}
+
+	return set
+}
+
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// analyze extracts themes, sentiment and entities from text using fixed
+// word lists, so the same text always gives the same result.
+func analyze(text string) v4.IndexResult {
+	ws := words(text)
+	present := wordSet(ws...)
+
+	res := v4.IndexResult{
+		Themes:   []string{},
+		Entities: []v4.Entity{},
+	}
+	for theme, hints := range themeWords {
+		for _, h := range hints {
+			if present[h] {
+				res.Themes = append(res.Themes, theme)
+				break
+			}
+		}
+	}
+	sort.Strings(res.Themes)
+
+	score := 0
+	for _, w := range ws {
+		if positiveWords[w] {
+			score++
+		} else if negativeWords[w] {
+			score--
+		}
+	}
+	switch {
+	case score > 0:
+		res.Sentiment = "pos"
+	case score < 0:
+		res.Sentiment = "neg"
+	default:
+		res.Sentiment = "neu"
+	}
+
+	for title, classes := range gazetteer {
+		if containsName(text, title) {
+			res.Entities = append(res.Entities, v4.Entity{Title: title, Classifications: classes})
+		}
+	}
+	sort.Slice(res.Entities, func(i, j int) bool {
+		return res.Entities[i].Title < res.Entities[j].Title
+	})
+
+	return res
+}
+
+// containsName reports whether name occurs in text as whole words.
+func containsName(text, name string) bool {
+	for i := 0; ; {
+		j := strings.Index(text[i:], name)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(name)
+		if !isWordByteAt(text, start-1) && !isWordByteAt(text, end) {
+			return true
+		}
+		i = start + 1
+	}
+}
+
+func isWordByteAt(s string, i int) bool {
+	if i < 0 || i >= len(s) {
+		return false
+	}
+	c := s[i]
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
@@ -0,0 +1,214 @@
+package v4test
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// matcher reports whether a stored document satisfies a query.
+type matcher func(d *document) bool
+
+// parseQuery compiles the search engine query syntax, e.g.
+// `(Location.city:* OR Person.politician:"Angela Merkel") AND NOT sentiment:neg`.
+func parseQuery(q string) (matcher, error) {
+	toks, err := tokenize(q)
+	if err != nil {
+		return nil, err
+	}
+	if len(toks) == 0 {
+		return nil, fmt.Errorf("empty query")
+	}
+
+	p := &queryParser{toks: toks}
+	m, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.toks) {
+		return nil, fmt.Errorf("unexpected %q", p.toks[p.pos])
+	}
+
+	return m, nil
+}
+
+func tokenize(q string) ([]string, error) {
+	var toks []string
+	runes := []rune(q)
+	for i := 0; i < len(runes); {
+		switch r := runes[i]; {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')':
+			toks = append(toks, string(r))
+			i++
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '(' && runes[i] != ')' {
+				if runes[i] == '"' {
+					for i++; i < len(runes) && runes[i] != '"'; i++ {
+						if runes[i] == '\\' {
+							i++
+						}
+					}
+					if i >= len(runes) {
+						return nil, fmt.Errorf("unterminated quoted value")
+					}
+				}
+				i++
+			}
+			toks = append(toks, string(runes[start:i]))
+		}
+	}
+
+	return toks, nil
+}
+
+type queryParser struct {
+	toks []string
+	pos  int
+}
+
+func (p *queryParser) peek() string {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+	return ""
+}
+
+func (p *queryParser) or() (matcher, error) {
+	ms := []matcher{}
+	for {
+		m, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		ms = append(ms, m)
+		if p.peek() != "OR" {
+			break
+		}
+		p.pos++
+	}
+
+	return func(d *document) bool {
+		for _, m := range ms {
+			if m(d) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+func (p *queryParser) and() (matcher, error) {
+	ms := []matcher{}
+	for {
+		m, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		ms = append(ms, m)
+		if p.peek() == "AND" {
+			p.pos++
+			continue
+		}
+		if next := p.peek(); next == "" || next == ")" || next == "OR" {
+			break
+		}
+	}
+
+	return func(d *document) bool {
+		for _, m := range ms {
+			if !m(d) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func (p *queryParser) not() (matcher, error) {
+	if p.peek() != "NOT" {
+		return p.primary()
+	}
+
+	p.pos++
+	m, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(d *document) bool { return !m(d) }, nil
+}
+
+func (p *queryParser) primary() (matcher, error) {
+	tok := p.peek()
+	switch tok {
+	case "":
+		return nil, fmt.Errorf("unexpected end of query")
+	case ")", "AND", "OR":
+		return nil, fmt.Errorf("unexpected %q", tok)
+	case "(":
+		p.pos++
+		m, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("unclosed parenthesis")
+		}
+		p.pos++
+		return m, nil
+	}
+
+	p.pos++
+	return term(tok)
+}
+
+func term(tok string) (matcher, error) {
+	i := strings.Index(tok, ":")
+	if i <= 0 || i == len(tok)-1 {
+		return nil, fmt.Errorf("bad query term %q", tok)
+	}
+	field, value := tok[:i], tok[i+1:]
+
+	switch field {
+	case "theme":
+		return func(d *document) bool {
+			for _, t := range d.Themes {
+				if t == value {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case "sentiment":
+		return func(d *document) bool { return d.Sentiment == value }, nil
+	}
+
+	if !strings.Contains(field, ".") {
+		return nil, fmt.Errorf("unknown query field %q", field)
+	}
+	if strings.HasPrefix(value, `"`) {
+		v, err := strconv.Unquote(value)
+		if err != nil {
+			return nil, fmt.Errorf("bad quoted value %s", value)
+		}
+		value = v
+	}
+
+	return func(d *document) bool {
+		for _, e := range d.Entities {
+			if value != "*" && !strings.EqualFold(e.Title, value) {
+				continue
+			}
+			for _, c := range e.Classifications {
+				if c == field {
+					return true
+				}
+			}
+		}
+		return false
+	}, nil
+}
@@ -0,0 +1,325 @@
+// Package v4test provides an in-memory fake of the Repustate API for tests
+// and offline development.
+//
+// The fake extracts themes, sentiment and entities with fixed word lists
+// rather than real language understanding, so results are deterministic:
+//
+//	srv := v4test.NewServer()
+//	defer srv.Close()
+//	c, _ := v4.New(v4.WithServerURL(srv.URL))
+package v4test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	v4 "github.com/repustate/rcli/api-client/v4"
+)
+
+var (
+	// languages accepted by the fake, matching the demo server
+	supportedLangs = map[string]bool{
+		"ar": true,
+		"de": true,
+		"en": true,
+		"es": true,
+		"fr": true,
+		"ru": true,
+		"zh": true,
+	}
+)
+
+// document is an indexed document along with its analysis.
+type document struct {
+	v4.IndexResult
+	Text     string
+	Lang     string
+	Metadata map[string]string
+	seq      int
+}
+
+// Handler serves the Repustate API endpoints from memory.
+// Every user has a separate index.
+type Handler struct {
+	basePath string
+
+	mu    sync.Mutex
+	seq   int
+	users map[string]map[string]*document
+}
+
+// NewHandler creates a fake API serving endpoints under basePath,
+// e.g. v4.DefaultBasePath.
+func NewHandler(basePath string) *Handler {
+	if basePath = strings.Trim(basePath, "/"); basePath != "" {
+		basePath = "/" + basePath
+	}
+
+	return &Handler{
+		basePath: basePath,
+		users:    map[string]map[string]*document{},
+	}
+}
+
+// NewServer starts a fake API on a local port using the default base path.
+// The caller should Close it when done.
+func NewServer() *httptest.Server {
+	return httptest.NewServer(NewHandler(v4.DefaultBasePath))
+}
+
+// Reset drops the documents of all users.
+func (h *Handler) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.users = map[string]map[string]*document{}
+}
+
+// Len returns the number of documents indexed by user.
+func (h *Handler) Len(user string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return len(h.users[user])
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rel := strings.TrimPrefix(path.Clean(r.URL.Path), h.basePath+"/")
+	if rel == r.URL.Path {
+		writeError(w, http.StatusNotFound, "unknown endpoint %s", r.URL.Path)
+		return
+	}
+
+	user := r.URL.Query().Get("username")
+	if user == "" {
+		writeError(w, http.StatusBadRequest, "username is required")
+		return
+	}
+
+	switch {
+	case rel == "index" && r.Method == http.MethodPost:
+		h.index(w, r, user)
+	case rel == "search" && r.Method == http.MethodGet:
+		h.search(w, r, user)
+	case strings.HasPrefix(rel, "documents/"):
+		id := strings.TrimPrefix(rel, "documents/")
+		switch r.Method {
+		case http.MethodGet:
+			h.get(w, user, id)
+		case http.MethodPut:
+			h.update(w, r, user, id)
+		case http.MethodDelete:
+			h.delete(w, user, id)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+		}
+	case rel == "index" || rel == "search":
+		writeError(w, http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+	default:
+		writeError(w, http.StatusNotFound, "unknown endpoint %s", r.URL.Path)
+	}
+}
+
+// readDocument decodes the text, language and metadata of an index or
+// update request.
+func readDocument(w http.ResponseWriter, r *http.Request) (*document, bool) {
+	var body struct {
+		ID       string            `json:"id"`
+		Text     string            `json:"text"`
+		Metadata map[string]string `json:"metadata"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "malformed request body: %v", err)
+		return nil, false
+	}
+	if strings.TrimSpace(body.Text) == "" {
+		writeError(w, http.StatusBadRequest, "text is required")
+		return nil, false
+	}
+
+	lang := r.URL.Query().Get("lang")
+	if lang == "" {
+		lang = "en"
+	}
+	if !supportedLangs[lang] {
+		writeError(w, http.StatusBadRequest, "unsupported language %q", lang)
+		return nil, false
+	}
+
+	d := &document{
+		IndexResult: analyze(body.Text),
+		Text:        body.Text,
+		Lang:        lang,
+		Metadata:    body.Metadata,
+	}
+	d.ID = body.ID
+
+	return d, true
+}
+
+func (h *Handler) index(w http.ResponseWriter, r *http.Request, user string) {
+	d, ok := readDocument(w, r)
+	if !ok {
+		return
+	}
+
+	if strings.ContainsAny(d.ID, "/?#") || d.ID == "." || d.ID == ".." {
+		writeError(w, http.StatusBadRequest, "bad document id %q", d.ID)
+		return
+	}
+
+	h.mu.Lock()
+	if h.users[user] == nil {
+		h.users[user] = map[string]*document{}
+	}
+	// a document indexed under the id of another one replaces it
+	if old, found := h.users[user][d.ID]; found && d.ID != "" {
+		d.seq = old.seq
+	} else {
+		h.seq++
+		d.seq = h.seq
+	}
+	if d.ID == "" {
+		d.ID = fmt.Sprintf("doc-%06d", d.seq)
+	}
+	h.users[user][d.ID] = d
+	h.mu.Unlock()
+
+	writeJSON(w, d.IndexResult)
+}
+
+func (h *Handler) search(w http.ResponseWriter, r *http.Request, user string) {
+	q := r.URL.Query()
+	match, err := parseQuery(q.Get("query"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "malformed query: %v", err)
+		return
+	}
+	limit, err := intParam(q.Get("limit"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "bad limit: %v", err)
+		return
+	}
+	offset, err := intParam(q.Get("offset"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "bad offset: %v", err)
+		return
+	}
+
+	h.mu.Lock()
+	var matches []*document
+	for _, d := range h.users[user] {
+		if match(d) {
+			matches = append(matches, d)
+		}
+	}
+	h.mu.Unlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].seq < matches[j].seq
+	})
+
+	res := v4.SearchResult{Total: len(matches), Documents: []v4.Document{}}
+	if offset < len(matches) {
+		matches = matches[offset:]
+		if limit > 0 && limit < len(matches) {
+			matches = matches[:limit]
+		}
+		for _, d := range matches {
+			res.Documents = append(res.Documents, d.document())
+		}
+	}
+
+	writeJSON(w, res)
+}
+
+func (h *Handler) get(w http.ResponseWriter, user, id string) {
+	h.mu.Lock()
+	d, ok := h.users[user][id]
+	h.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "document %q not found", id)
+		return
+	}
+
+	writeJSON(w, d.document())
+}
+
+func (h *Handler) update(w http.ResponseWriter, r *http.Request, user, id string) {
+	d, ok := readDocument(w, r)
+	if !ok {
+		return
+	}
+
+	h.mu.Lock()
+	old, found := h.users[user][id]
+	if found {
+		d.ID = id
+		d.seq = old.seq
+		if d.Metadata == nil {
+			d.Metadata = old.Metadata
+		}
+		h.users[user][id] = d
+	}
+	h.mu.Unlock()
+	if !found {
+		writeError(w, http.StatusNotFound, "document %q not found", id)
+		return
+	}
+
+	writeJSON(w, d.IndexResult)
+}
+
+func (h *Handler) delete(w http.ResponseWriter, user, id string) {
+	h.mu.Lock()
+	_, found := h.users[user][id]
+	delete(h.users[user], id)
+	h.mu.Unlock()
+	if !found {
+		writeError(w, http.StatusNotFound, "document %q not found", id)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (d *document) document() v4.Document {
+	return v4.Document{
+		ID:       d.ID,
+		Text:     d.Text,
+		Entities: d.Entities,
+		Metadata: d.Metadata,
+	}
+}
+
+func intParam(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err == nil && n < 0 {
+		return 0, fmt.Errorf("%d is negative", n)
+	}
+
+	return n, err
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, format string, args ...interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error": fmt.Sprintf(format, args...),
+	})
+}
@@ -0,0 +1,129 @@
+package v4test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	res := analyze("Lionel Messi won the football match in Madrid, a great game despite the rain.")
+	if want := []string{"sports", "weather"}; !reflect.DeepEqual(res.Themes, want) {
+		t.Errorf("themes = %v, want %v", res.Themes, want)
+	}
+	if res.Sentiment != "pos" {
+		t.Errorf("sentiment = %q, want pos", res.Sentiment)
+	}
+	var titles []string
+	for _, e := range res.Entities {
+		titles = append(titles, e.Title)
+	}
+	if want := []string{"Lionel Messi", "Madrid"}; !reflect.DeepEqual(titles, want) {
+		t.Errorf("entities = %v, want %v", titles, want)
+	}
+
+	// names are whole words
+	if res := analyze("Parisian bakeries"); len(res.Entities) != 0 || res.Sentiment != "neu" {
+		t.Errorf("analyze() = %+v, want no entities and a neutral sentiment", res)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	docs := []*document{
+		{Text: "good weather in Paris"},
+		{Text: "bad weather in Berlin"},
+		{Text: "Angela Merkel won the election"},
+	}
+	for _, d := range docs {
+		d.IndexResult = analyze(d.Text)
+	}
+
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"theme:weather", []int{0, 1}},
+		{"sentiment:neg", []int{1}},
+		{"Location.city:*", []int{0, 1}},
+		{"Location.city:paris", []int{0}},
+		{`Person.politician:"Angela Merkel"`, []int{2}},
+		{"theme:weather AND NOT sentiment:neg", []int{0}},
+		{"theme:weather sentiment:pos", []int{0}},
+		{"sentiment:neg OR theme:politics", []int{1, 2}},
+		{"(Location.city:* OR theme:politics) AND sentiment:pos", []int{0, 2}},
+	}
+
+	for _, tt := range tests {
+		m, err := parseQuery(tt.query)
+		if err != nil {
+			t.Errorf("parseQuery(%q) error = %v", tt.query, err)
+			continue
+		}
+		var got []int
+		for i, d := range docs {
+			if m(d) {
+				got = append(got, i)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q matches %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	for _, q := range []string{"", "weather", "theme:", "(theme:weather", "theme:weather)", `Person.politician:"Angela`, "Foo:bar", "AND theme:weather"} {
+		if _, err := parseQuery(q); err == nil {
+			t.Errorf("parseQuery(%q) succeeded", q)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	h := NewHandler("/demo/")
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return w
+	}
+
+	for i := 0; i < 3; i++ {
+		if w := do(http.MethodPost, "/demo/index?username=ann&lang=en", `{"text": "The weather is good."}`); w.Code != http.StatusOK {
+			t.Fatalf("index responded %d: %s", w.Code, w.Body)
+		}
+	}
+	if h.Len("ann") != 3 || h.Len("bob") != 0 {
+		t.Errorf("Len() = %d, %d", h.Len("ann"), h.Len("bob"))
+	}
+
+	w := do(http.MethodGet, "/demo/search?username=ann&query=theme:weather&limit=2&offset=2", "")
+	if want := `{"total":3,"matches":[{"id":"doc-000003","text":"The weather is good.","entities":[]}]}`; strings.TrimSpace(w.Body.String()) != want {
+		t.Errorf("search = %s, want %s", w.Body, want)
+	}
+
+	tests := []struct {
+		method, target, body string
+		code                 int
+	}{
+		{http.MethodGet, "/demo/search?query=theme:weather", "", http.StatusBadRequest},
+		{http.MethodGet, "/demo/search?username=ann&query=theme:weather&limit=-1", "", http.StatusBadRequest},
+		{http.MethodGet, "/other/search?username=ann", "", http.StatusNotFound},
+		{http.MethodDelete, "/demo/index?username=ann", "", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/demo/index?username=ann&lang=xx", `{"text": "Text."}`, http.StatusBadRequest},
+		{http.MethodPost, "/demo/index?username=ann", `{"text": ""}`, http.StatusBadRequest},
+		{http.MethodGet, "/demo/documents/doc-000001?username=ann", "", http.StatusOK},
+		{http.MethodPut, "/demo/documents/doc-000009?username=ann", `{"text": "Text."}`, http.StatusNotFound},
+		{http.MethodDelete, "/demo/documents/doc-000001?username=ann", "", http.StatusNoContent},
+		{http.MethodGet, "/demo/documents/doc-000001?username=ann", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if w := do(tt.method, tt.target, tt.body); w.Code != tt.code {
+			t.Errorf("%s %s responded %d, want %d: %s", tt.method, tt.target, w.Code, tt.code, w.Body)
+		}
+	}
+
+	h.Reset()
+	if h.Len("ann") != 0 {
+		t.Error("Reset() kept documents")
+	}
+}
@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	api "github.com/repustate/rcli/api-client/v4"
+)
+
+const tokenFlag = "token"
+
+// newAuthCmd represents the credential management commands
+func newAuthCmd(p *profile) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "auth",
+		Short: "Manage credentials of connection profiles",
+		Long: `Store, remove and show credentials used to connect to a Repustate server.
+
+Credentials are stored per connection profile in ` + credentialsFilename + ` in the
+configuration directory, readable by its owner only. RCLI_API_KEY, when set,
+takes precedence over stored credentials.`,
+	}
+
+	cmd.AddCommand(
+		newAuthLoginCmd(p),
+		newAuthLogoutCmd(p),
+		newAuthStatusCmd(p),
+	)
+
+	return cmd
+}
+
+func newAuthLoginCmd(p *profile) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "login",
+		Short: "Store an API key for the selected profile",
+		Long: `Store an API key, or a bearer token with '--token', for the selected profile.
+
+The secret is prompted for without echo, or read from the standard input
+when it is not a terminal:
+echo "$REPUSTATE_KEY" | rcli auth login --profile prod`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			useToken, _ := cmd.Flags().GetBool(tokenFlag)
+			kind := "API key"
+			if useToken {
+				kind = "token"
+			}
+
+			secret, err := readSecret(fmt.Sprintf("Enter %s: ", kind))
+			if err != nil {
+				printErr(fmt.Sprintf("failed to read %s: %v", kind, err))
+				return
+			}
+			if secret == "" {
+				printErr(fmt.Sprintf("%s must not be empty", kind))
+				return
+			}
+
+			creds, err := loadCredentials()
+			if err != nil {
+				printErr(err.Error())
+				return
+			}
+			if useToken {
+				creds[p.Name] = credential{Token: secret}
+			} else {
+				creds[p.Name] = credential{APIKey: secret}
+			}
+			if err := storeCredentials(creds); err != nil {
+				printErr(fmt.Sprintf("failed to store credentials: %v", err))
+				return
+			}
+
+			printMsg(fmt.Sprintf("Stored %s %s for profile %q.", kind, api.Redact(secret), p.Name))
+		},
+		Example: "auth login\r\nauth login --profile prod --token",
+	}
+
+	cmd.Flags().Bool(tokenFlag, false, "Store a bearer token instead of an API key")
+
+	return cmd
+}
+
+func newAuthLogoutCmd(p *profile) *cobra.Command {
+	return &cobra.Command{
+		Use:   "logout",
+		Short: "Remove stored credentials of the selected profile",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			creds, err := loadCredentials()
+			if err != nil {
+				printErr(err.Error())
+				return
+			}
+			if _, ok := creds[p.Name]; !ok {
+				printMsg(fmt.Sprintf("No stored credentials for profile %q.", p.Name))
+				return
+			}
+
+			delete(creds, p.Name)
+			if err := storeCredentials(creds); err != nil {
+				printErr(fmt.Sprintf("failed to remove credentials: %v", err))
+				return
+			}
+
+			printMsg(fmt.Sprintf("Removed credentials of profile %q.", p.Name))
+		},
+	}
+}
+
+func newAuthStatusCmd(p *profile) *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show how the selected profile authenticates",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Printf("Profile: %s\nServer:  %s\n", p.Name, p.Server)
+			switch {
+			case p.Token != "":
+				fmt.Printf("Auth:    bearer token %s\nSource:  %s\n", api.Redact(p.Token), p.AuthSource)
+			case p.APIKey != "":
+				fmt.Printf("Auth:    API key %s\nSource:  %s\n", api.Redact(p.APIKey), p.AuthSource)
+			default:
+				fmt.Println("Auth:    none")
+				printMsg("Use `rcli auth login` to store an API key.")
+			}
+		},
+	}
+}
+
+// readSecret prompts for a secret without echoing it when the standard
+// input is a terminal, otherwise reads the first line of the input.
+func readSecret(prompt string) (string, error) {
+	fd := os.Stdin.Fd()
+	if isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd) {
+		fmt.Fprint(os.Stderr, prompt)
+		secret, err := term.ReadPassword(int(fd))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(secret)), nil
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
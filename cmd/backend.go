@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	api "github.com/repustate/rcli/api-client/v4"
+)
+
+const (
+	debugFlag = "debug"
+	rateFlag  = "rate"
+)
+
+// identity returns the user whose index commands work on. It is only
+// called once a command runs, after global flags were parsed.
+type identity func() string
+
+// backend forwards to the api.Service configured from global flags
+// before any subcommand runs, so commands can be created up front.
+type backend struct {
+	api.Service
+}
+
+// newService creates the API client of the given profile, wrapped in the
+// decorators requested by global flags and settings.
+func newService(cmd *cobra.Command, p profile, s settings) (api.Service, error) {
+	debug, _ := cmd.Flags().GetBool(debugFlag)
+	logger := log.New(os.Stderr, "rcli: ", log.LstdFlags|log.Lmicroseconds)
+
+	opts := append(p.clientOptions(), clientNetOptions(cmd)...)
+	if debug {
+		opts = append(opts, api.WithRequestLog(logger))
+	}
+	c, err := api.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// logged durations leave out the time spent waiting for the rate limit
+	var svc api.Service = newExpiryTrackingService(&c, p.IndexTTL)
+	if s.Mirror {
+		svc = newMirroringService(svc)
+	}
+	if debug {
+		svc = api.NewLoggingService(svc, logger)
+	}
+	if rate, _ := cmd.Flags().GetFloat64(rateFlag); rate > 0 {
+		svc = api.NewRateLimitedService(svc, rate)
+	}
+
+	return svc, nil
+}
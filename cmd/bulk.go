@@ -0,0 +1,583 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-isatty"
+	"github.com/pkg/errors"
+
+	api "github.com/repustate/rcli/api-client/v4"
+	"github.com/repustate/rcli/cmd/chunk"
+	"github.com/repustate/rcli/cmd/extract"
+	"github.com/repustate/rcli/cmd/langid"
+	"github.com/repustate/rcli/cmd/subtitle"
+)
+
+// metadata of the passages of a split document, set along with the
+// metadata of the document itself
+const (
+	// metaParent is shared by the passages of a document, its id when
+	// it has one or else a hash of its content and source
+	metaParent = "parent"
+	// metaChunk is the position of a passage, starting at 1
+	metaChunk = "chunk"
+	// metaChunks is the number of passages of the document
+	metaChunks = "chunks"
+	// metaSource is the file or input the document was read from
+	metaSource = "source"
+	// metaStart and metaEnd are the timecodes of the cues of a subtitle
+	// file making up a passage
+	metaStart = "start"
+	metaEnd   = "end"
+)
+
+// bulkDoc is a single document of a bulk index run.
+type bulkDoc struct {
+	Path string
+	// Text is indexed instead of the content of Path when set
+	Text string
+	// Lang overrides the language of the run when set
+	Lang string
+	// Replaces is the mirror key of an earlier copy of the document
+	Replaces string
+	// ID is the id to index the document under, chosen by the server
+	// when empty
+	ID string
+	// Metadata is carried along with the document
+	Metadata map[string]string
+	// Err tells why the document could not be read, e.g. a malformed
+	// row of a structured file
+	Err error
+	// Chunk is the position of a passage among the Chunks passages of
+	// a split document, 0 for documents indexed whole
+	Chunk, Chunks int
+}
+
+// bulkResult is the outcome of indexing a single bulkDoc.
+type bulkResult struct {
+	Doc  bulkDoc
+	Hash string
+	Res  *api.IndexResult
+	Err  error
+	// Lang is the detected language of the document, unless it was given
+	Lang langid.Result
+	// Skipped is set for documents indexed by a previous run of the job
+	Skipped bool
+
+	// text and lang are sent to the server, once the document is read
+	text, lang string
+}
+
+// bulkSummary aggregates the outcome of a bulk index run.
+type bulkSummary struct {
+	Total   int
+	Indexed int
+	Skipped int
+	// Split counts the documents split into passages, each passage
+	// being counted as a document otherwise
+	Split     int
+	Failed    []bulkResult
+	Results   []bulkResult
+	Themes    map[string]int
+	Sentiment map[string]int
+	// Languages counts indexed documents per detected language
+	Languages map[string]int
+}
+
+func newBulkSummary(total int) *bulkSummary {
+	return &bulkSummary{
+		Total:     total,
+		Themes:    map[string]int{},
+		Sentiment: map[string]int{},
+		Languages: map[string]int{},
+	}
+}
+
+// add counts r, keeping it without the text of its document, which the
+// summary of a run over a large file or mailbox cannot afford to hold.
+func (s *bulkSummary) add(r bulkResult) {
+	r.Doc.Text, r.text = "", ""
+	s.Results = append(s.Results, r)
+	if r.Skipped {
+		s.Skipped++
+		return
+	}
+	if r.Err != nil {
+		s.Failed = append(s.Failed, r)
+		return
+	}
+
+	s.Indexed++
+	for _, t := range r.Res.Themes {
+		s.Themes[t]++
+	}
+	if r.Res.Sentiment != "" {
+		s.Sentiment[r.Res.Sentiment]++
+	}
+	if r.Lang.Lang != "" {
+		s.Languages[r.Lang.Lang]++
+	}
+}
+
+// collectFiles expands file names, glob patterns and, with recursive set,
+// directories into a list of regular files. Hidden files and directories
+// found while walking a directory are skipped.
+func collectFiles(patterns []string, recursive bool) ([]string, error) {
+	var files []string
+	seen := map[string]bool{}
+	add := func(f string) {
+		if !seen[f] {
+			seen[f] = true
+			files = append(files, f)
+		}
+	}
+
+	for _, pattern := range patterns {
+		matches := []string{pattern}
+		if strings.ContainsAny(pattern, "*?[") {
+			var err error
+			matches, err = filepath.Glob(pattern)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "bad pattern %q", pattern)
+			}
+			if len(matches) == 0 {
+				return nil, errors.Errorf("no files match %q", pattern)
+			}
+		}
+
+		for _, m := range matches {
+			info, err := os.Stat(m)
+			if err != nil {
+				return nil, err
+			}
+			if !info.IsDir() {
+				add(m)
+				continue
+			}
+			if !recursive {
+				return nil, errors.Errorf("%s is a directory, use '--recursive' to index its files", m)
+			}
+
+			err = filepath.Walk(m, func(p string, info os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				hidden := p != m && strings.HasPrefix(info.Name(), ".")
+				if info.IsDir() {
+					if hidden {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if !hidden && info.Mode().IsRegular() {
+					add(p)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return files, nil
+}
+
+// sendDocs feeds docs to a bulk run until ctx is done.
+func sendDocs(ctx context.Context, docs []bulkDoc) <-chan bulkDoc {
+	ch := make(chan bulkDoc)
+	go func() {
+		defer close(ch)
+		for _, doc := range docs {
+			select {
+			case ch <- doc:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch
+}
+
+// indexBulk indexes docs through a pool of concurrency workers, reporting
+// progress on stderr and checkpointing every outcome in the job manifest.
+// total is the number of docs, 0 when it is not known up front.
+// Without lang, the language of every document is detected.
+// Documents longer than the chunk size of the job are indexed as several
+// passages, each counting as a document.
+// Documents not yet indexed when ctx is done are left out of the summary.
+func indexBulk(ctx context.Context, c api.Indexer, j *job, docs <-chan bulkDoc, total int, lang, user string, concurrency int) *bulkSummary {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	// documents are read and split by a first pool of workers, so that
+	// the passages of a long document are indexed in parallel too
+	passages := make(chan bulkResult)
+	results := make(chan bulkResult)
+
+	var prepared, indexed sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		prepared.Add(1)
+		go func() {
+			defer prepared.Done()
+			for doc := range docs {
+				for _, r := range prepareBulkDoc(j, doc, lang) {
+					if r.Err != nil || r.Skipped {
+						results <- r
+					} else {
+						passages <- r
+					}
+				}
+			}
+		}()
+
+		indexed.Add(1)
+		go func() {
+			defer indexed.Done()
+			for r := range passages {
+				results <- indexBulkDoc(ctx, c, r, user)
+			}
+		}()
+	}
+
+	go func() {
+		prepared.Wait()
+		close(passages)
+		indexed.Wait()
+		close(results)
+	}()
+
+	summary := newBulkSummary(total)
+	progress := newProgress(total)
+	var recordErr error
+	for r := range results {
+		// interrupted requests did not fail, they are just left for --resume
+		if r.Err != nil && ctx.Err() != nil {
+			continue
+		}
+
+		if r.Doc.Chunk == 1 {
+			summary.Split++
+			// the passages take the place of the document
+			if total != 0 {
+				summary.Total += r.Doc.Chunks - 1
+				progress.total += r.Doc.Chunks - 1
+			}
+		}
+		summary.add(r)
+		if !r.Skipped && recordErr == nil {
+			recordErr = j.record(r)
+			if recordErr != nil {
+				progress.clear()
+				printErr(fmt.Sprintf("Failed to update job manifest: %v", recordErr))
+			}
+		}
+		if r.Err != nil {
+			progress.clear()
+			printErr(fmt.Sprintf("Failed to index %s: %v", r.Doc.Path, r.Err))
+		}
+		printRenderErr(renderBulkResult(os.Stdout, output, r))
+		progress.update(summary.Indexed+summary.Skipped, len(summary.Failed))
+	}
+	progress.clear()
+	if total == 0 {
+		summary.Total = len(summary.Results)
+	}
+
+	var entities []api.Entity
+	for _, r := range summary.Results {
+		if r.Res != nil {
+			entities = append(entities, r.Res.Entities...)
+		}
+	}
+	rememberEntities(entities)
+
+	return summary
+}
+
+// prepareBulkDoc reads doc and splits it into the passages to index, the
+// document itself being the single passage when it is short enough.
+// Passages indexed by a previous run of the job are marked as skipped.
+func prepareBulkDoc(j *job, doc bulkDoc, lang string) []bulkResult {
+	if doc.Err != nil {
+		return []bulkResult{{Doc: doc, Err: doc.Err}}
+	}
+
+	data := []byte(doc.Text)
+	if doc.Text == "" {
+		var err error
+		if data, err = ioutil.ReadFile(doc.Path); err != nil {
+			return []bulkResult{{Doc: doc, Err: err}}
+		}
+	}
+	if doc.Lang != "" {
+		lang = doc.Lang
+	}
+
+	hash := docHash(doc, data)
+	if j.isDone(hash) {
+		return []bulkResult{{Doc: doc, Hash: hash, Skipped: true}}
+	}
+
+	text := doc.Text
+	if text == "" {
+		var err error
+		if text, err = extractText(doc.Path, data); err != nil {
+			return []bulkResult{{Doc: doc, Hash: hash, Err: err}}
+		}
+	}
+
+	// the language of the whole document is more reliably detected than
+	// the one of its passages
+	detected := langid.Result{}
+	if lang == "" {
+		detected = detectLang(text)
+		lang = detected.Lang
+	}
+
+	passages := splitDoc(doc.Path, data, text, j.chunking(), j.CueWindow)
+	if len(passages) == 1 && passages[0].meta == nil {
+		return []bulkResult{{Doc: doc, Hash: hash, Lang: detected, text: text, lang: lang}}
+	}
+
+	// the same text read from another input, or split differently, has
+	// other passages
+	parent := doc.ID
+	if parent == "" {
+		o := j.chunking()
+		parent = contentHash([]byte(fmt.Sprintf("%s\x00%s\x00%d\x00%d\x00%d", hash, doc.Path, o.Size, o.Overlap, j.CueWindow)))[:16]
+	}
+	results := make([]bulkResult, len(passages))
+	for i, p := range passages {
+		d := doc
+		d.Path = fmt.Sprintf("%s#%d", doc.Path, i+1)
+		d.Text = ""
+		d.Chunk, d.Chunks = i+1, len(passages)
+		if doc.ID != "" {
+			d.ID = fmt.Sprintf("%s-%d", doc.ID, i+1)
+		}
+		d.Metadata = make(map[string]string, len(doc.Metadata)+len(p.meta)+4)
+		for k, v := range doc.Metadata {
+			d.Metadata[k] = v
+		}
+		for k, v := range p.meta {
+			d.Metadata[k] = v
+		}
+		d.Metadata[metaParent] = parent
+		d.Metadata[metaChunk] = strconv.Itoa(i + 1)
+		d.Metadata[metaChunks] = strconv.Itoa(len(passages))
+		d.Metadata[metaSource] = doc.Path
+
+		h := docHash(d, []byte(p.text))
+		results[i] = bulkResult{Doc: d, Hash: h, Lang: detected, Skipped: j.isDone(h), text: p.text, lang: lang}
+	}
+
+	return results
+}
+
+// passage is a part of a split document, with metadata of its own.
+type passage struct {
+	text string
+	meta map[string]string
+}
+
+// splitDoc returns the passages of the document read from path with the
+// given content and text: the cue windows of subtitles, or else chunks.
+func splitDoc(path string, data []byte, text string, o chunk.Options, window time.Duration) []passage {
+	if subtitle.Is(extract.TypeOf(path, data)) {
+		if cues, err := subtitle.Parse(data); err == nil {
+			windows := subtitle.Windows(cues, window)
+			passages := make([]passage, len(windows))
+			for i, w := range windows {
+				passages[i] = passage{text: w.Text, meta: map[string]string{
+					metaStart: subtitle.Timecode(w.Start),
+					metaEnd:   subtitle.Timecode(w.End),
+				}}
+			}
+			return passages
+		}
+	}
+
+	chunks := chunk.Split(text, o)
+	passages := make([]passage, len(chunks))
+	for i, c := range chunks {
+		passages[i] = passage{text: c.Text}
+	}
+
+	return passages
+}
+
+// indexBulkDoc sends a document or passage prepared by prepareBulkDoc.
+func indexBulkDoc(ctx context.Context, c api.Indexer, r bulkResult, user string) bulkResult {
+	var opts []api.IndexOption
+	if r.Doc.ID != "" {
+		opts = append(opts, api.WithDocumentID(r.Doc.ID))
+	}
+	if len(r.Doc.Metadata) != 0 {
+		opts = append(opts, api.WithMetadata(r.Doc.Metadata))
+	}
+
+	r.Res, r.Err = c.IndexContext(withSource(ctx, r.Doc.Path), r.text, r.lang, user, opts...)
+	// the summary outlives the text of the document
+	r.text, r.Doc.Text = "", ""
+
+	return r
+}
+
+// extractText returns the plain text of a file, without the markup of
+// formats such as HTML, PDF or DOCX.
+func extractText(path string, data []byte) (text string, err error) {
+	// a malformed file fails on its own rather than taking a whole bulk
+	// run down with it
+	defer func() {
+		if r := recover(); r != nil {
+			text, err = "", errors.Errorf("failed to extract text: %v", r)
+		}
+	}()
+
+	text, _, err = extract.Text(path, data)
+	if err != nil {
+		return "", errors.WithMessage(err, "failed to extract text")
+	}
+
+	return text, nil
+}
+
+// docHash identifies the content of doc for resuming jobs. The id and
+// metadata of a document read from a structured file are part of it,
+// as rows may share their text.
+func docHash(doc bulkDoc, data []byte) string {
+	if doc.ID == "" && len(doc.Metadata) == 0 {
+		return contentHash(data)
+	}
+
+	key, _ := json.Marshal(struct {
+		ID       string            `json:"id"`
+		Text     string            `json:"text"`
+		Metadata map[string]string `json:"metadata"`
+	}{doc.ID, string(data), doc.Metadata})
+	return contentHash(key)
+}
+
+// progress renders a single self-updating status line on stderr when
+// it is a terminal.
+type progress struct {
+	total   int
+	enabled bool
+}
+
+func newProgress(total int) *progress {
+	return &progress{
+		total:   total,
+		enabled: isatty.IsTerminal(os.Stderr.Fd()) || isatty.IsCygwinTerminal(os.Stderr.Fd()),
+	}
+}
+
+func (p *progress) update(done, failed int) {
+	if !p.enabled {
+		return
+	}
+	if p.total == 0 {
+		fmt.Fprintf(os.Stderr, "\rIndexed %d documents, %d failed", done+failed, failed)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\rIndexed %d/%d documents, %d failed", done+failed, p.total, failed)
+}
+
+func (p *progress) clear() {
+	if !p.enabled {
+		return
+	}
+	fmt.Fprint(os.Stderr, "\r\033[K")
+}
+
+func printBulkSummary(s *bulkSummary, j *job) {
+	if output.format != outputText {
+		printRenderErr(renderBulkSummary(os.Stdout, output, s, j))
+		return
+	}
+
+	unsent := s.Total - s.Indexed - s.Skipped - len(s.Failed)
+	msg := fmt.Sprintf("Indexed %d of %d documents, %d failed", s.Indexed, s.Total, len(s.Failed))
+	if s.Skipped > 0 {
+		msg += fmt.Sprintf(", %d already indexed", s.Skipped)
+	}
+	if unsent > 0 {
+		msg += fmt.Sprintf(", %d not sent", unsent)
+	}
+	if s.Split > 0 {
+		msg += fmt.Sprintf(", counting the passages of %d split documents", s.Split)
+	}
+	if len(s.Failed) == 0 && unsent == 0 {
+		printMsg(msg + ".")
+	} else {
+		printErr(msg + ".")
+	}
+
+	if len(s.Failed) != 0 {
+		fmt.Println("Failed documents:")
+		for _, r := range s.Failed {
+			fmt.Printf("- %s: %v\n", r.Doc.Path, r.Err)
+		}
+		fmt.Printf("Failed documents are listed in %s\n", j.deadLetterPath())
+	}
+	if len(s.Failed) != 0 || unsent > 0 {
+		command := "index"
+		if j.Mirror != "" {
+			command = "reindex"
+		}
+		fmt.Printf("Run `rcli %s --resume %s` to retry the remaining documents.\n", command, j.ID)
+	}
+	if s.Indexed == 0 {
+		return
+	}
+
+	if len(s.Themes) == 0 {
+		fmt.Println("No themes detected.")
+	} else {
+		fmt.Println("Themes:")
+		for _, t := range sortedByCount(s.Themes) {
+			fmt.Printf("- %s (%d)\n", t, s.Themes[t])
+		}
+	}
+
+	fmt.Println("Sentiment:")
+	for _, sent := range sortedByCount(s.Sentiment) {
+		fmt.Printf("- %s (%d)\n", sentimentName(sent), s.Sentiment[sent])
+	}
+
+	if len(s.Languages) != 0 {
+		fmt.Println("Detected languages:")
+		for _, lang := range sortedByCount(s.Languages) {
+			fmt.Printf("- %s (%d)\n", lang, s.Languages[lang])
+		}
+	}
+}
+
+// sortedByCount returns keys of m ordered by descending count, then by name.
+func sortedByCount(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if m[keys[i]] != m[keys[j]] {
+			return m[keys[i]] > m[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	return keys
+}
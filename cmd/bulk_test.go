@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	api "github.com/repustate/rcli/api-client/v4"
+)
+
+func TestBulkSummaryAdd(t *testing.T) {
+	s := newBulkSummary(3)
+	s.add(bulkResult{Doc: bulkDoc{Path: "a.txt", Text: "The weather is good."}, Res: &api.IndexResult{ID: "a", Sentiment: "pos", Themes: []string{"weather"}}})
+	s.add(bulkResult{Doc: bulkDoc{Path: "b.txt", Text: "Unreadable."}, Err: errors.New("bad file")})
+	s.add(bulkResult{Doc: bulkDoc{Path: "c.txt", Text: "Indexed before."}, Skipped: true, text: "Indexed before."})
+
+	if s.Indexed != 1 || s.Skipped != 1 || len(s.Failed) != 1 {
+		t.Errorf("indexed %d, skipped %d, failed %d, want 1 each", s.Indexed, s.Skipped, len(s.Failed))
+	}
+	if s.Themes["weather"] != 1 || s.Sentiment["pos"] != 1 {
+		t.Errorf("themes %v, sentiment %v", s.Themes, s.Sentiment)
+	}
+	// the summary of a large run must not hold the text of every document
+	for _, r := range append(s.Results, s.Failed...) {
+		if r.Doc.Text != "" || r.text != "" {
+			t.Errorf("the summary keeps the text of %s", r.Doc.Path)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+// Package chunk splits long documents into passages of about the same
+// length, so that a search matches the part of a document it is about
+// rather than the whole of it.
+//
+// Passages end at paragraph or sentence boundaries. Sentences are told
+// apart by the punctuation of European languages, Arabic and Chinese.
+// Consecutive passages may overlap by a few sentences, keeping the context
+// of a sentence at the start of a passage.
+package chunk
+
+import (
+	"regexp"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Options tell how to split a document.
+type Options struct {
+	// Size is the length of a passage in characters a document is split
+	// into, documents are not split when it is 0.
+	Size int `json:"size"`
+	// Overlap is the number of characters at the end of a passage which
+	// are repeated at the start of the next one, at most.
+	Overlap int `json:"overlap"`
+}
+
+// Passage is a part of a document.
+type Passage struct {
+	Text string
+	// Start and End are the byte offsets of Text in the document.
+	Start, End int
+}
+
+// span is a sentence, or a piece of a sentence too long for a passage.
+type span struct {
+	start, end int
+	// runes before start and end
+	runeStart, runeEnd int
+	// paraEnd is set for the last sentence of a paragraph
+	paraEnd bool
+}
+
+var paragraphBreak = regexp.MustCompile(`\n[ \t\r\f\v]*\n`)
+
+// Split returns the passages of text, the whole text being the single
+// passage of documents no longer than o.Size.
+func Split(text string, o Options) []Passage {
+	if o.Size <= 0 || utf8.RuneCountInString(text) <= o.Size {
+		return []Passage{{Text: text, Start: 0, End: len(text)}}
+	}
+	if o.Overlap >= o.Size {
+		o.Overlap = o.Size / 2
+	}
+
+	spans := splitLong(text, sentences(text), o.Size)
+	if len(spans) == 0 {
+		return []Passage{{Text: text, Start: 0, End: len(text)}}
+	}
+
+	var passages []Passage
+	for i := 0; i < len(spans); {
+		first := spans[i]
+		// take sentences while they fit
+		j := i + 1
+		for j < len(spans) && spans[j].runeEnd-first.runeStart <= o.Size {
+			j++
+		}
+		// rather end at a paragraph when it leaves the passage half full
+		for k := j - 1; k > i && j < len(spans); k-- {
+			if spans[k].paraEnd && spans[k].runeEnd-first.runeStart >= o.Size/2 {
+				j = k + 1
+				break
+			}
+		}
+
+		last := spans[j-1]
+		passages = append(passages, Passage{
+			Text:  text[first.start:last.end],
+			Start: first.start,
+			End:   last.end,
+		})
+		if j == len(spans) {
+			break
+		}
+
+		// start the next passage with the last sentences fitting the overlap
+		next := j
+		for next-1 > i && last.runeEnd-spans[next-1].runeStart <= o.Overlap {
+			next--
+		}
+		// as long as the next sentence fits along
+		for next < j && spans[j].runeEnd-spans[next].runeStart > o.Size {
+			next++
+		}
+		i = next
+	}
+
+	return passages
+}
+
+// Sentences returns the sentences of text, without the whitespace
+// around them.
+func Sentences(text string) []string {
+	spans := sentences(text)
+	s := make([]string, len(spans))
+	for i, sp := range spans {
+		s[i] = text[sp.start:sp.end]
+	}
+
+	return s
+}
+
+// sentences finds the sentences of the paragraphs of text.
+func sentences(text string) []span {
+	var spans []span
+	add := func(start, end int, paraEnd bool) {
+		for start < end {
+			r, size := utf8.DecodeRuneInString(text[start:])
+			if !unicode.IsSpace(r) {
+				break
+			}
+			start += size
+		}
+		for end > start {
+			r, size := utf8.DecodeLastRuneInString(text[:end])
+			if !unicode.IsSpace(r) {
+				break
+			}
+			end -= size
+		}
+		if start < end {
+			spans = append(spans, span{start: start, end: end})
+		}
+		if paraEnd && len(spans) != 0 {
+			spans[len(spans)-1].paraEnd = true
+		}
+	}
+
+	paraStart := 0
+	breaks := append(paragraphBreak.FindAllStringIndex(text, -1), []int{len(text), len(text)})
+	for _, br := range breaks {
+		para := text[paraStart:br[0]]
+		start := 0
+		for _, end := range boundaries(para) {
+			add(paraStart+start, paraStart+end, false)
+			start = end
+		}
+		add(paraStart+start, paraStart+len(para), true)
+		paraStart = br[1]
+	}
+
+	// rune offsets, for measuring passages in characters
+	pos, runes := 0, 0
+	for i := range spans {
+		runes += utf8.RuneCountInString(text[pos:spans[i].start])
+		spans[i].runeStart = runes
+		runes += utf8.RuneCountInString(text[spans[i].start:spans[i].end])
+		spans[i].runeEnd = runes
+		pos = spans[i].end
+	}
+
+	return spans
+}
+
+// splitLong cuts sentences longer than size characters at the last
+// whitespace fitting, or anywhere in scripts without spaces.
+func splitLong(text string, spans []span, size int) []span {
+	var out []span
+	for _, sp := range spans {
+		for sp.runeEnd-sp.runeStart > size {
+			cut, cutRunes := sp.start, 0
+			lastSpace, lastSpaceRunes, lastSpaceSize := -1, 0, 0
+			for cutRunes < size {
+				r, n := utf8.DecodeRuneInString(text[cut:])
+				if unicode.IsSpace(r) {
+					lastSpace, lastSpaceRunes, lastSpaceSize = cut, cutRunes, n
+				}
+				cut += n
+				cutRunes++
+			}
+			next, nextRunes := cut, cutRunes
+			if lastSpace > sp.start {
+				cut, cutRunes = lastSpace, lastSpaceRunes
+				next, nextRunes = lastSpace+lastSpaceSize, lastSpaceRunes+1
+			}
+			out = append(out, span{start: sp.start, end: cut, runeStart: sp.runeStart, runeEnd: sp.runeStart + cutRunes})
+			sp.start, sp.runeStart = next, sp.runeStart+nextRunes
+		}
+		out = append(out, sp)
+	}
+
+	return out
+}
@@ -0,0 +1,116 @@
+package chunk
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSentences(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"The weather is good. It is sunny!", []string{"The weather is good.", "It is sunny!"}},
+		{"Is it raining?! \"Yes.\" Take an umbrella.", []string{"Is it raining?!", "\"Yes.\"", "Take an umbrella."}},
+		{"Dr. Smith met Mr. Jones at 3.30 p.m. on Monday.", []string{"Dr. Smith met Mr. Jones at 3.30 p.m. on Monday."}},
+		{"J. R. R. Tolkien wrote it. See example.com for more.", []string{"J. R. R. Tolkien wrote it.", "See example.com for more."}},
+		{"Es regnet, z.B. in Berlin. Morgen nicht.", []string{"Es regnet, z.B. in Berlin.", "Morgen nicht."}},
+		{"It costs approx. ten euros.", []string{"It costs approx. ten euros."}},
+		{"今天天气很好。明天会下雨！", []string{"今天天气很好。", "明天会下雨！"}},
+		{"الطقس جميل اليوم؟ نعم.", []string{"الطقس جميل اليوم؟", "نعم."}},
+		{"First paragraph without a period\n\nSecond paragraph.", []string{"First paragraph without a period", "Second paragraph."}},
+		{"  \n\n ", []string{}},
+	}
+
+	for _, tt := range tests {
+		if got := Sentences(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Sentences(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSplitShort(t *testing.T) {
+	text := "A short document."
+	for _, o := range []Options{{}, {Size: 100, Overlap: 10}, {Size: len(text)}} {
+		want := []Passage{{Text: text, Start: 0, End: len(text)}}
+		if got := Split(text, o); !reflect.DeepEqual(got, want) {
+			t.Errorf("Split(%+v) = %+v, want %+v", o, got, want)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	var paragraphs []string
+	for p := 0; p < 6; p++ {
+		var sentences []string
+		for s := 0; s < 5; s++ {
+			sentences = append(sentences, strings.Repeat("word ", 3+(p+s)%7)+"end.")
+		}
+		paragraphs = append(paragraphs, strings.Join(sentences, " "))
+	}
+	long := strings.Repeat("x", 150)
+	texts := map[string]string{
+		"paragraphs":    strings.Join(paragraphs, "\n\n"),
+		"long sentence": "Before. " + strings.Repeat("averyverylongword ", 30) + "after. End.",
+		"no spaces":     strings.Repeat("天气很好", 100),
+		"long word":     "Start. " + long + " end.",
+	}
+
+	for name, text := range texts {
+		for _, o := range []Options{{Size: 100, Overlap: 0}, {Size: 100, Overlap: 30}, {Size: 60, Overlap: 100}} {
+			passages := Split(text, o)
+			if len(passages) < 2 {
+				t.Errorf("%s: Split(%+v) = %d passages", name, o, len(passages))
+				continue
+			}
+			overlap := o.Overlap
+			if overlap >= o.Size {
+				overlap = o.Size / 2
+			}
+
+			for i, p := range passages {
+				if p.Text != text[p.Start:p.End] {
+					t.Errorf("%s: passage %d text %q is not at %d-%d", name, i, p.Text, p.Start, p.End)
+				}
+				if n := utf8.RuneCountInString(p.Text); n > o.Size {
+					t.Errorf("%s: passage %d has %d characters, more than %d", name, i, n, o.Size)
+				}
+				if i == 0 {
+					continue
+				}
+				prev := passages[i-1]
+				// every passage goes on with new text, after the overlap
+				if p.End <= prev.End || p.Start <= prev.Start {
+					t.Errorf("%s: passage %d (%d-%d) does not move past passage %d (%d-%d)", name, i, p.Start, p.End, i-1, prev.Start, prev.End)
+				}
+				if p.Start < prev.End && utf8.RuneCountInString(text[p.Start:prev.End]) > overlap {
+					t.Errorf("%s: passages %d and %d overlap by more than %d characters", name, i-1, i, overlap)
+				}
+				if gap := strings.TrimSpace(text[prev.End:max(prev.End, p.Start)]); gap != "" {
+					t.Errorf("%s: the text %q between passages %d and %d is lost", name, gap, i-1, i)
+				}
+			}
+			if first, last := passages[0], passages[len(passages)-1]; first.Start != 0 || last.End != len(strings.TrimSpace(text)) {
+				t.Errorf("%s: passages cover %d-%d of %d bytes", name, first.Start, last.End, len(text))
+			}
+		}
+	}
+}
+
+func TestSplitParagraphs(t *testing.T) {
+	// a passage rather ends with a paragraph than half way through the next
+	text := strings.Repeat("Some words here. ", 4) + "\n\n" + strings.Repeat("More words there. ", 4)
+	passages := Split(text, Options{Size: 100})
+	if len(passages) != 2 || !strings.HasSuffix(passages[0].Text, "Some words here.") {
+		t.Errorf("Split() = %q, want the first paragraph as a passage", passages)
+	}
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,108 @@
+package chunk
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	// terminators ending a sentence wherever they are, in Chinese and
+	// Japanese text words are not separated by spaces
+	fullWidthTerminators = "。！？｡．"
+
+	// terminators ending a sentence when followed by a space, in European
+	// languages and Arabic
+	terminators = ".!?…؟۔"
+
+	// closing quotes and brackets belonging to the sentence before them
+	closers = "\"'”’»)]}」』）】›"
+
+	// abbreviations whose period does not end a sentence, lower-cased
+	abbreviations = map[string]bool{
+		// English
+		"mr": true, "mrs": true, "ms": true, "dr": true, "prof": true, "st": true,
+		"jr": true, "sr": true, "vs": true, "etc": true, "e.g": true, "i.e": true,
+		"inc": true, "ltd": true, "co": true, "no": true, "fig": true, "approx": true,
+		// German
+		"z.b": true, "bzw": true, "usw": true, "nr": true, "ca": true, "vgl": true,
+		"d.h": true, "u.a": true, "hr": true, "fr": true,
+		// Spanish
+		"sra": true, "srta": true, "ud": true, "uds": true, "pág": true,
+		// French
+		"mme": true, "mlle": true, "cf": true, "p": true, "env": true,
+	}
+)
+
+// boundaries returns the byte offsets at which the sentences of a
+// paragraph end.
+func boundaries(para string) []int {
+	var ends []int
+	for i := 0; i < len(para); {
+		r, size := utf8.DecodeRuneInString(para[i:])
+		i += size
+
+		full := strings.ContainsRune(fullWidthTerminators, r)
+		if !full && !strings.ContainsRune(terminators, r) {
+			continue
+		}
+		period := r == '.'
+
+		// "?!", "...", and closing quotes are part of the sentence
+		end := i
+		for end < len(para) {
+			next, n := utf8.DecodeRuneInString(para[end:])
+			if !strings.ContainsRune(terminators+fullWidthTerminators+closers, next) {
+				break
+			}
+			period = period && next != '!' && next != '?'
+			end += n
+		}
+
+		if !full {
+			next, _ := utf8.DecodeRuneInString(para[end:])
+			if end < len(para) && !unicode.IsSpace(next) {
+				// e.g. "3.14", "example.com" or "a.m."
+				i = end
+				continue
+			}
+			if period && !endsSentence(para[:i-1], para[end:]) {
+				i = end
+				continue
+			}
+		}
+
+		if end < len(para) {
+			ends = append(ends, end)
+		}
+		i = end
+	}
+
+	return ends
+}
+
+// endsSentence tells whether the period between before and after ends
+// a sentence rather than an abbreviation or initial.
+func endsSentence(before, after string) bool {
+	word := before
+	if i := strings.LastIndexFunc(before, unicode.IsSpace); i >= 0 {
+		word = before[i+1:]
+	}
+	word = strings.TrimLeft(word, closers+"(")
+
+	// initials such as "J. R. R. Tolkien"
+	if r, size := utf8.DecodeRuneInString(word); size == len(word) && unicode.IsUpper(r) {
+		return false
+	}
+	if abbreviations[strings.ToLower(word)] {
+		return false
+	}
+
+	// a sentence does not go on in lower case, e.g. after "approx."
+	next := strings.TrimLeftFunc(after, unicode.IsSpace)
+	if r, _ := utf8.DecodeRuneInString(next); unicode.IsLower(r) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,241 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	api "github.com/repustate/rcli/api-client/v4"
+	"github.com/repustate/rcli/api-client/v4/v4test"
+)
+
+// newTestClient returns a client of a fake server, keeping the files of
+// the commands in a temporary home directory.
+func newTestClient(t *testing.T) *api.Client {
+	t.Helper()
+	home := tempDir(t)
+	setenv(t, "HOME", home)
+	setenv(t, "XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+
+	srv := v4test.NewServer()
+	t.Cleanup(srv.Close)
+	c, err := api.New(api.WithServerURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &c
+}
+
+// tempDir creates a directory removed at the end of the test.
+func tempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "rcli-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	return dir
+}
+
+// setenv sets an environment variable for the duration of the test.
+func setenv(t *testing.T, key, value string) {
+	t.Helper()
+	old, ok := os.LookupEnv(key)
+	if err := os.Setenv(key, value); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+// runCmd runs cmd with args and the CSV output format, returning the
+// records it wrote to the standard output and its messages.
+func runCmd(t *testing.T, cmd *cobra.Command, args ...string) ([][]string, string) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs, err := ioutil.TempFile(tempDir(t), "stderr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer msgs.Close()
+	stdout, stderr, colorErr := os.Stdout, os.Stderr, color.Error
+	os.Stdout, os.Stderr, color.Error = w, msgs, msgs
+	output = outputOptions{format: outputCSV}
+	defer func() {
+		os.Stdout, os.Stderr, color.Error = stdout, stderr, colorErr
+		output = outputOptions{format: outputText}
+	}()
+
+	out := make(chan []byte)
+	go func() {
+		data, _ := ioutil.ReadAll(r)
+		out <- data
+	}()
+	cmd.SetArgs(args)
+	cmd.SetOut(ioutil.Discard)
+	cmd.SetErr(ioutil.Discard)
+	err = cmd.ExecuteContext(context.Background())
+	w.Close()
+	data := <-out
+	if err != nil {
+		t.Fatalf("%s %v: %v", cmd.Name(), args, err)
+	}
+
+	var records [][]string
+	if len(data) != 0 {
+		if records, err = csv.NewReader(bytes.NewReader(data)).ReadAll(); err != nil {
+			t.Fatalf("%s %v wrote bad CSV %q: %v", cmd.Name(), args, data, err)
+		}
+	}
+
+	data, err = ioutil.ReadFile(msgs.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return records, string(data)
+}
+
+func TestIndexSearchCmd(t *testing.T) {
+	c := newTestClient(t)
+	user := func() string { return "ann" }
+	p := &profile{}
+
+	texts := []string{
+		"Angela Merkel met Emmanuel Macron in Paris, the weather was good.",
+		"The weather in London is bad.",
+		"Stocks fell in Berlin.",
+	}
+	for i, text := range texts {
+		records, msgs := runCmd(t, newIndexCmd(c, user, p), "-t", text, "-l", "en")
+		if len(records) != 2 {
+			t.Fatalf("index wrote %q, messages %q", records, msgs)
+		}
+		if want := []string{"doc-00000" + string(rune('1'+i))}; !reflect.DeepEqual(records[1][3:], want) {
+			t.Errorf("index id = %v, want %v", records[1][3:], want)
+		}
+	}
+
+	records, msgs := runCmd(t, newSearchCmd(c, user, p), "theme:weather", "AND", "NOT", "neg")
+	want := [][]string{{"doc-000001", texts[0]}}
+	if got := idsAndTexts(records); !reflect.DeepEqual(got, want) {
+		t.Errorf("search = %q, want %q (messages %q)", got, want, msgs)
+	}
+
+	records, _ = runCmd(t, newSearchCmd(c, user, p), "--limit", "1", "--page", "2", "theme:weather")
+	want = [][]string{{"doc-000002", texts[1]}}
+	if got := idsAndTexts(records); !reflect.DeepEqual(got, want) {
+		t.Errorf("search of the second page = %q, want %q", got, want)
+	}
+
+	records, _ = runCmd(t, newSearchCmd(c, user, p), "--all", "--limit", "1", "theme:weather")
+	want = [][]string{{"doc-000001", texts[0]}, {"doc-000002", texts[1]}}
+	if got := idsAndTexts(records); !reflect.DeepEqual(got, want) {
+		t.Errorf("search of all pages = %q, want %q", got, want)
+	}
+
+	// documents of other users are not found
+	records, _ = runCmd(t, newSearchCmd(c, func() string { return "bob" }, p), "theme:weather")
+	if got := idsAndTexts(records); len(got) != 0 {
+		t.Errorf("search of another user = %q, want no matches", got)
+	}
+
+	records, msgs = runCmd(t, newSearchCmd(c, user, p), "theme:weather", "AND")
+	if records != nil || !strings.Contains(msgs, "  theme:weather AND\n") {
+		t.Errorf("search of a bad query wrote %q, messages %q", records, msgs)
+	}
+}
+
+// idsAndTexts returns the ids and texts of search result records.
+func idsAndTexts(records [][]string) [][]string {
+	var docs [][]string
+	for i, r := range records {
+		if i == 0 {
+			continue // header
+		}
+		docs = append(docs, []string{r[2], r[0]})
+	}
+
+	return docs
+}
+
+func TestIndexFilesCmd(t *testing.T) {
+	c := newTestClient(t)
+	user := func() string { return "ann" }
+	p := &profile{}
+
+	dir := tempDir(t)
+	files := map[string]string{
+		"a.txt": "The weather in Paris is good.",
+		"b.txt": "Stocks fell in Berlin.",
+	}
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records, msgs := runCmd(t, newIndexCmd(c, user, p), "-l", "en", filepath.Join(dir, "*"))
+	if len(records) != 3 {
+		t.Fatalf("index wrote %q, messages %q", records, msgs)
+	}
+	statuses := map[string]string{}
+	for _, r := range records[1:] {
+		statuses[filepath.Base(r[0])] = r[1]
+	}
+	if want := map[string]string{"a.txt": "indexed", "b.txt": "indexed"}; !reflect.DeepEqual(statuses, want) {
+		t.Errorf("index statuses = %v, want %v (messages %q)", statuses, want, msgs)
+	}
+
+	records, _ = runCmd(t, newSearchCmd(c, user, p), "Location.city=Berlin")
+	if got := idsAndTexts(records); len(got) != 1 || got[0][1] != "Stocks fell in Berlin." {
+		t.Errorf("search = %q, want the text of b.txt", got)
+	}
+}
+
+func TestDocCmd(t *testing.T) {
+	c := newTestClient(t)
+	user := func() string { return "ann" }
+	p := &profile{}
+
+	runCmd(t, newIndexCmd(c, user, p), "-t", "The weather in London is bad.", "-l", "en")
+
+	records, msgs := runCmd(t, newDocCmd(c, user, p), "update", "doc-000001", "-t", "The weather in London is good.", "-l", "en")
+	if len(records) != 2 {
+		t.Fatalf("doc update wrote %q, messages %q", records, msgs)
+	}
+
+	records, _ = runCmd(t, newDocCmd(c, user, p), "get", "doc-000001")
+	if len(records) != 2 || records[1][0] != "The weather in London is good." {
+		t.Errorf("doc get = %q, want the updated text", records)
+	}
+
+	records, _ = runCmd(t, newDocCmd(c, user, p), "delete", "doc-000001")
+	if len(records) != 2 {
+		t.Errorf("doc delete wrote %q", records)
+	}
+
+	records, msgs = runCmd(t, newDocCmd(c, user, p), "get", "doc-000001")
+	if records != nil || !strings.Contains(msgs, "No such document") {
+		t.Errorf("doc get of a deleted document wrote %q, messages %q", records, msgs)
+	}
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	bashShell       = "bash"
+	zshShell        = "zsh"
+	fishShell       = "fish"
+	powerShellShell = "powershell"
+)
+
+// powerShellCompletion asks the program itself for completions through
+// cobra's hidden __complete command, so dynamic query term completion
+// works the same as in the other shells.
+const powerShellCompletion = `# powershell completion for %[1]s
+
+Register-ArgumentCompleter -Native -CommandName '%[1]s' -ScriptBlock {
+    param($WordToComplete, $CommandAst, $CursorPosition)
+
+    # the command line up to the cursor
+    $Command = "$($CommandAst.CommandElements)"
+    if ($Command.Length -gt $CursorPosition) {
+        $Command = $Command.Substring(0, $CursorPosition)
+    }
+    $Program, $Arguments = $Command.Split(" ", 2)
+    $RequestComp = "$Program __complete $Arguments"
+    # an empty word has to be passed explicitly to be completed
+    if ($WordToComplete -eq "") {
+        $RequestComp = "$RequestComp" + ' ""'
+    }
+
+    $Out = @(Invoke-Expression -Command "$RequestComp" 2>$null)
+    if ($Out.Count -eq 0) {
+        return
+    }
+
+    # the last line holds the completion directive, e.g. ":4"
+    $Directive = [int]$Out[-1].TrimStart(':')
+    $Out = $Out[0..($Out.Count - 2)] | Where-Object { $_ -ne "" }
+
+    # ShellCompDirectiveError
+    if (($Directive -band 1) -ne 0) {
+        return
+    }
+    # ShellCompDirectiveNoFileComp, fall back to file names otherwise
+    if (@($Out).Count -eq 0 -and ($Directive -band 4) -eq 0) {
+        return $null
+    }
+
+    $Out | ForEach-Object {
+        $Name, $Description = $_.Split("` + "`" + `t", 2)
+        if (-not $Description) {
+            $Description = $Name
+        }
+        [System.Management.Automation.CompletionResult]::new($Name, $Name, 'ParameterValue', $Description)
+    }
+}
+`
+
+func newCompletionCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "completion [bash|zsh|fish|powershell]",
+		Short: "Generate shell completion script",
+		Long: `Generate a completion script for the given shell, completing commands,
+flags and search query terms.
+
+Bash:
+  $ source <(rcli completion bash)
+  # to load completions for each session, once:
+  $ rcli completion bash > /etc/bash_completion.d/rcli
+
+Zsh:
+  # if shell completion is not already enabled, once:
+  $ echo "autoload -U compinit; compinit" >> ~/.zshrc
+  # to load completions for each session, once:
+  $ rcli completion zsh > "${fpath[1]}/_rcli"
+
+Fish:
+  $ rcli completion fish | source
+  # to load completions for each session, once:
+  $ rcli completion fish > ~/.config/fish/completions/rcli.fish
+
+PowerShell:
+  PS> rcli completion powershell | Out-String | Invoke-Expression
+  # to load completions for each session, add the output to your profile:
+  PS> rcli completion powershell >> $PROFILE`,
+		DisableFlagsInUseLine: true,
+		ValidArgs:             []string{bashShell, zshShell, fishShell, powerShellShell},
+		Args:                  cobra.ExactValidArgs(1),
+		// generating scripts needs neither an identity nor a server connection
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := genCompletion(cmd.Root(), args[0], os.Stdout); err != nil {
+				printErr(fmt.Sprintf("failed to generate completion script: %v", err))
+				os.Exit(1)
+			}
+		},
+	}
+
+	return cmd
+}
+
+func genCompletion(root *cobra.Command, shell string, w io.Writer) error {
+	switch shell {
+	case bashShell:
+		return root.GenBashCompletion(w)
+	case zshShell:
+		return root.GenZshCompletion(w)
+	case fishShell:
+		return root.GenFishCompletion(w, true)
+	case powerShellShell:
+		_, err := fmt.Fprintf(w, powerShellCompletion, root.Name())
+		return err
+	}
+
+	return fmt.Errorf("unsupported shell %q", shell)
+}
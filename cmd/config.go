@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// newConfigCmd represents the config file commands
+func newConfigCmd() *cobra.Command {
+	var keys []string
+	for _, name := range settingNames() {
+		keys = append(keys, fmt.Sprintf("  %-10s %s", name, settingKeys[name].usage))
+	}
+
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Show and change preferences of the config file",
+		Long: `Show and change preferences kept in the config file, which command-line
+flags and environment variables take precedence over.
+
+Keys:
+` + strings.Join(keys, "\n"),
+		// a broken config file must not prevent fixing it
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+	}
+
+	cmd.AddCommand(
+		newConfigGetCmd(),
+		newConfigSetCmd(),
+		newConfigListCmd(),
+		newConfigPathCmd(),
+	)
+
+	return cmd
+}
+
+func newConfigGetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               "get <key>",
+		Short:             "Print the value of a key",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeSettingName,
+		Run: func(cmd *cobra.Command, args []string) {
+			key, ok := settingKeys[args[0]]
+			if !ok {
+				printErr(fmt.Sprintf("unknown config key %q, known keys: %v", args[0], settingNames()))
+				return
+			}
+
+			s, err := loadSettings()
+			if err != nil {
+				printErr(err.Error())
+				return
+			}
+
+			fmt.Println(key.get(&s))
+		},
+		Example: "config get output",
+	}
+}
+
+func newConfigSetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               "set <key> [value]",
+		Short:             "Change the value of a key, without a value the key is reset",
+		Args:              cobra.RangeArgs(1, 2),
+		ValidArgsFunction: completeSettingName,
+		Run: func(cmd *cobra.Command, args []string) {
+			key, ok := settingKeys[args[0]]
+			if !ok {
+				printErr(fmt.Sprintf("unknown config key %q, known keys: %v", args[0], settingNames()))
+				return
+			}
+			var value string
+			if len(args) > 1 {
+				value = args[1]
+			}
+
+			s, err := loadSettings()
+			if err != nil {
+				printErr(err.Error())
+				return
+			}
+			if err := key.set(&s, value); err != nil {
+				printErr(err.Error())
+				return
+			}
+			if err := storeSettings(s); err != nil {
+				printErr(fmt.Sprintf("failed to store config: %v", err))
+			}
+		},
+		Example: "config set output table\r\nconfig set server http://127.0.0.1:9000\r\nconfig set server",
+	}
+}
+
+func newConfigListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "Print all keys and their values",
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			s, err := loadSettings()
+			if err != nil {
+				printErr(err.Error())
+				return
+			}
+
+			for _, name := range settingNames() {
+				fmt.Printf("%s=%s\n", name, settingKeys[name].get(&s))
+			}
+		},
+	}
+}
+
+func newConfigPathCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "path",
+		Short: "Print the location of the config file",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Println(configPath())
+		},
+	}
+}
+
+// completeSettingName completes the first argument with config file keys.
+func completeSettingName(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) != 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var names []string
+	for _, name := range settingNames() {
+		if strings.HasPrefix(name, toComplete) {
+			names = append(names, name)
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
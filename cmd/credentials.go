@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	credentialsFilename = "credentials.yaml"
+
+	// apiKeyEnv overrides stored credentials of the selected profile
+	apiKeyEnv = "RCLI_API_KEY"
+)
+
+// credential authenticates requests to a Repustate server, either with
+// an API key or a bearer token.
+type credential struct {
+	APIKey string `yaml:"api_key,omitempty"`
+	Token  string `yaml:"token,omitempty"`
+}
+
+// credentialsFile maps profile names to their credentials. It is kept
+// apart from other settings, readable by its owner only.
+type credentialsFile map[string]credential
+
+func credentialsPath() string {
+	return filepath.Join(getConfigDir(), credentialsFilename)
+}
+
+func loadCredentials() (credentialsFile, error) {
+	creds := credentialsFile{}
+
+	data, err := ioutil.ReadFile(credentialsPath())
+	if os.IsNotExist(err) {
+		return creds, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(data, &creds); err != nil {
+		return nil, errors.WithMessagef(err, "bad credentials file %s", credentialsPath())
+	}
+
+	return creds, nil
+}
+
+func storeCredentials(creds credentialsFile) error {
+	data, err := yaml.Marshal(creds)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(credentialsPath(), data)
+}
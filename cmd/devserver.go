@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	api "github.com/repustate/rcli/api-client/v4"
+	"github.com/repustate/rcli/api-client/v4/v4test"
+)
+
+const (
+	addrFlag     = "addr"
+	basePathFlag = "base-path"
+)
+
+// newDevServerCmd represents the local fake server command
+func newDevServerCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dev-server",
+		Short: "Run a local fake Repustate server for offline development",
+		Long: `Run an in-memory fake of the Repustate API on a local port.
+
+The fake extracts themes, sentiment and entities from a small fixed vocabulary,
+so results are deterministic but far less rich than the real engine's.
+Documents are kept in memory only and are gone once the server stops.
+
+Point rcli at it from another shell with:
+RCLI_SERVER=http://127.0.0.1:9000 rcli index -t "I love Paris"`,
+		Args: cobra.NoArgs,
+		// the fake server needs neither an identity nor a server connection
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+		Run: func(cmd *cobra.Command, args []string) {
+			addr := cmd.Flag(addrFlag).Value.String()
+			basePath := cmd.Flag(basePathFlag).Value.String()
+
+			l, err := net.Listen("tcp", addr)
+			if err != nil {
+				printErr(fmt.Sprintf("failed to listen: %v", err))
+				return
+			}
+
+			srv := &http.Server{Handler: v4test.NewHandler(basePath)}
+			go func() {
+				<-cmd.Context().Done()
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				srv.Shutdown(ctx)
+			}()
+
+			printMsg(fmt.Sprintf("Serving fake Repustate API on http://%s, press Ctrl-C to stop.", l.Addr()))
+			fmt.Printf("Use it with: RCLI_SERVER=http://%s rcli ...\n", l.Addr())
+			if err := srv.Serve(l); err != nil && err != http.ErrServerClosed {
+				printErr(fmt.Sprintf("server failed: %v", err))
+			}
+		},
+		Example: "dev-server\r\ndev-server --addr=:8080",
+	}
+
+	cmd.Flags().String(addrFlag, "127.0.0.1:9000", "Address to listen on")
+	cmd.Flags().String(basePathFlag, api.DefaultBasePath, "Path prefix of the API endpoints")
+
+	return cmd
+}
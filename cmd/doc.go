@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	api "github.com/repustate/rcli/api-client/v4"
+	"github.com/repustate/rcli/cmd/langid"
+)
+
+// newDocCmd represents the document management commands
+func newDocCmd(c api.DocumentStore, user identity, p *profile) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "doc",
+		Short: "Get, update or delete indexed documents",
+		Long: `Manage documents of the semantic search index by their id.
+
+Document ids are printed by 'rcli index' and next to every 'rcli search' match.`,
+	}
+
+	cmd.AddCommand(
+		newDocGetCmd(c, user),
+		newDocUpdateCmd(c, user, p),
+		newDocDeleteCmd(c, user),
+	)
+
+	return cmd
+}
+
+func newDocGetCmd(c api.DocumentStore, user identity) *cobra.Command {
+	return &cobra.Command{
+		Use:   "get <id>",
+		Short: "Show an indexed document",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			doc, err := c.GetContext(cmd.Context(), args[0], user())
+			printDocument(doc, err)
+		},
+		Example: "doc get 5f8d0d55b54764421b7156c3",
+	}
+}
+
+func newDocUpdateCmd(c api.DocumentStore, user identity, p *profile) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "update <id>",
+		Short: "Replace the text of an indexed document",
+		Long: `Replace the text of an indexed document, which is then analyzed again.
+
+The new text is given with '--text', or read from a file with '--file' whose
+text is extracted the way 'rcli index' does, e.g. from HTML, PDF or DOCX files.
+'--file -' reads the new text from the standard input.
+
+Valid language codes: ` + strings.Join(validLangs, ", "),
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			text := cmd.Flag(textFlag).Value.String()
+			filename := cmd.Flag(fileFlag).Value.String()
+			lang := cmd.Flag(langFlag).Value.String()
+
+			if text == "" && filename == "" {
+				printErr("one of '--text' or '--file' is required")
+				cmd.Usage()
+				return
+			}
+			if text != "" && filename != "" {
+				printErr("only one of '--text' or '--file' should be used")
+				cmd.Usage()
+				return
+			}
+			if lang != "" {
+				if err := checkLang(lang); err != nil {
+					printErr(err.Error())
+					return
+				}
+			}
+
+			switch {
+			case filename == stdinPath:
+				data, err := ioutil.ReadAll(os.Stdin)
+				if err != nil {
+					printErr(fmt.Sprintf("failed to read standard input: %v", err))
+					return
+				}
+				if strings.TrimSpace(string(data)) == "" {
+					printErr("no text to index on the standard input")
+					return
+				}
+				text = string(data)
+			case filename != "":
+				data, err := ioutil.ReadFile(filename)
+				if err != nil {
+					msg := fmt.Sprintf("failed to read file: %v", err)
+					printErr(msg)
+					return
+				}
+				// the same file gives the same text as when it was indexed
+				if text, err = extractText(filename, data); err != nil {
+					printErr(err.Error())
+					return
+				}
+			}
+
+			detected := langid.Result{}
+			if lang == "" {
+				lang = p.Lang
+			}
+			if lang == "" {
+				detected = detectLang(text)
+				lang = detected.Lang
+			}
+			res, err := c.UpdateContext(cmd.Context(), args[0], text, lang, user())
+			printIndexResult(res, err, detected)
+		},
+		Example: "doc update 5f8d0d55b54764421b7156c3 --text=\"Paris is the capital of France.\" -l=en\r\ndoc update 5f8d0d55b54764421b7156c3 -f report.pdf\r\ndoc update 5f8d0d55b54764421b7156c3 -f - < notes.txt",
+	}
+
+	cmd.Flags().StringP(textFlag, "t", "", "New text of the document")
+	cmd.Flags().StringP(fileFlag, "f", "", "File with the new text of the document, - for the standard input")
+	cmd.MarkFlagFilename(fileFlag)
+	cmd.Flags().StringP(langFlag, "l", "", "Content language (default is detected)")
+
+	return cmd
+}
+
+func newDocDeleteCmd(c api.DocumentStore, user identity) *cobra.Command {
+	return &cobra.Command{
+		Use:     "delete <id>",
+		Aliases: []string{"rm"},
+		Short:   "Remove a document from the index",
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			err := c.DeleteContext(cmd.Context(), args[0], user())
+			printDeleteResult(args[0], err)
+		},
+		Example: "doc delete 5f8d0d55b54764421b7156c3",
+	}
+}
+
+func printDocument(doc *api.Document, err error) {
+	if err != nil {
+		printErr(fmt.Sprintf("Failed to get document: %v", err))
+		printDocErrHint(err)
+	} else if output.format != outputText {
+		printRenderErr(renderDocument(os.Stdout, output, doc))
+	} else {
+		fmt.Printf("ID: %s\n\n%s\n\nEntities:\n", doc.ID, doc.Text)
+		for _, entity := range doc.Entities {
+			classes := strings.Join(entity.Classifications, ", ")
+			fmt.Printf("\t%q (%s)\n", entity.Title, classes)
+		}
+		printMetadata(doc.Metadata)
+	}
+}
+
+func printDeleteResult(id string, err error) {
+	if err != nil {
+		printErr(fmt.Sprintf("Failed to delete document: %v", err))
+		printDocErrHint(err)
+	} else if output.format != outputText {
+		printRenderErr(renderDeleteResult(os.Stdout, output, id))
+	} else {
+		printMsg(fmt.Sprintf("Document %s deleted.", id))
+	}
+}
+
+func printDocErrHint(err error) {
+	if api.IsNotFound(err) {
+		printMsg("No such document, it may have been deleted or your index may have expired.")
+		return
+	}
+	printErrHint(err, "Check the document id.")
+}
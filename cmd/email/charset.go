@@ -0,0 +1,64 @@
+package email
+
+import (
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// charsetEncoding returns the encoding of a charset name, as browsers
+// understand it: latin1 text, which mail clients send windows-1252 text as
+// all the time, is read as windows-1252.
+func charsetEncoding(charset string) (encoding.Encoding, error) {
+	switch strings.ToLower(strings.TrimSpace(charset)) {
+	case "utf-16":
+		// RFC 2781 text is big endian unless its byte order mark tells
+		// otherwise, where browsers default to little endian
+		return unicode.UTF16(unicode.BigEndian, unicode.UseBOM), nil
+	}
+
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, errors.Errorf("unsupported charset %q", charset)
+	}
+
+	return enc, nil
+}
+
+// decodeCharset converts text in the named charset to UTF-8. Text claimed
+// to be ASCII or UTF-8 which is not is read as windows-1252.
+func decodeCharset(charset string, data []byte) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(charset)) {
+	case "", "us-ascii", "ascii", "utf-8", "utf8":
+		if utf8.Valid(data) {
+			return string(data), nil
+		}
+		charset = "windows-1252"
+	}
+
+	enc, err := charsetEncoding(charset)
+	if err != nil {
+		return "", err
+	}
+	text, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return "", errors.WithMessagef(err, "bad %s text", charset)
+	}
+
+	return string(text), nil
+}
+
+// charsetReader lets mime.WordDecoder decode the charsets of this package.
+func charsetReader(charset string, r io.Reader) (io.Reader, error) {
+	enc, err := charsetEncoding(charset)
+	if err != nil {
+		return nil, err
+	}
+
+	return enc.NewDecoder().Reader(r), nil
+}
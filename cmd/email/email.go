@@ -0,0 +1,286 @@
+// Package email reads the messages of mail archives, mbox files and
+// Maildir folders, and turns them into the text a person wrote.
+//
+// The text of a message is taken from its plain text part, or from its
+// HTML part when it has no plain text alternative. Attachments are left
+// out, and so are the quoted replies and the signature of the sender:
+//
+//	msg, err := email.Parse(data)
+//	fmt.Println(msg.Subject, msg.From, msg.Text)
+package email
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/repustate/rcli/cmd/extract"
+)
+
+// maxDepth bounds the nesting of multipart entities and forwarded messages
+const maxDepth = 16
+
+// blockquoteTag matches the opening and closing tags of the quoted replies
+// of HTML messages
+var blockquoteTag = regexp.MustCompile(`(?i)<(/?)blockquote\b[^>]*>`)
+
+// Message is a parsed email message.
+type Message struct {
+	Subject string
+	// From is the sender, as "Name <address>" or the address alone
+	From string
+	// Date is the zero time when the message has no valid date
+	Date time.Time
+	// Text is the body of the message, without quoted replies and the
+	// signature
+	Text string
+}
+
+// Parse reads a message in the Internet Message Format of RFC 5322.
+func Parse(data []byte) (*Message, error) {
+	return parse(data, 0)
+}
+
+func parse(data []byte, depth int) (*Message, error) {
+	m, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.WithMessage(err, "malformed message")
+	}
+
+	dec := &mime.WordDecoder{CharsetReader: charsetReader}
+	msg := &Message{Subject: decodeHeader(dec, m.Header.Get("Subject"))}
+
+	parser := mail.AddressParser{WordDecoder: dec}
+	if from, err := parser.Parse(m.Header.Get("From")); err == nil {
+		msg.From = from.Address
+		if from.Name != "" {
+			msg.From = fmt.Sprintf("%s <%s>", from.Name, from.Address)
+		}
+	} else {
+		msg.From = decodeHeader(dec, m.Header.Get("From"))
+	}
+	if date, err := m.Header.Date(); err == nil {
+		msg.Date = date
+	}
+
+	text, err := entityText(textproto.MIMEHeader(m.Header), m.Body, depth)
+	if err != nil {
+		return nil, err
+	}
+	msg.Text = Strip(text)
+
+	return msg, nil
+}
+
+// decodeHeader decodes the encoded words of a header, leaving the words
+// in unknown charsets as they are. Headers sent unencoded in another
+// charset than UTF-8 are read like bodies without a charset.
+func decodeHeader(dec *mime.WordDecoder, v string) string {
+	decoded, err := dec.DecodeHeader(v)
+	if err != nil {
+		decoded = v
+	}
+	if text, err := decodeCharset("", []byte(decoded)); err == nil {
+		decoded = text
+	}
+
+	return strings.Join(strings.Fields(decoded), " ")
+}
+
+// entityText returns the text of a MIME entity with the given header and
+// body, preferring plain text among alternative parts.
+func entityText(h textproto.MIMEHeader, body io.Reader, depth int) (string, error) {
+	if depth > maxDepth {
+		return "", errors.New("too deeply nested message parts")
+	}
+	if disposition, _, _ := mime.ParseMediaType(h.Get("Content-Disposition")); disposition == "attachment" {
+		return "", nil
+	}
+
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		// RFC 2045 defaults
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	body = transferDecoder(h.Get("Content-Transfer-Encoding"), body)
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		return multipartText(mediaType, params["boundary"], body, depth)
+	}
+
+	switch mediaType {
+	case "text/plain", "text/html", "message/rfc822":
+	default:
+		// images, documents and other attachments
+		return "", nil
+	}
+
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return "", errors.WithMessagef(err, "bad %s part", mediaType)
+	}
+	if mediaType == "message/rfc822" {
+		forwarded, err := parse(data, depth+1)
+		if err != nil {
+			return "", err
+		}
+		return forwarded.Text, nil
+	}
+
+	text, err := decodeCharset(params["charset"], data)
+	if err != nil {
+		return "", err
+	}
+	if mediaType == "text/html" {
+		return extract.HTML([]byte(stripQuotes(text)))
+	}
+	if strings.EqualFold(params["format"], "flowed") {
+		text = unflow(text, strings.EqualFold(params["delsp"], "yes"))
+	}
+
+	return text, nil
+}
+
+// stripQuotes removes the quoted replies of an HTML message, along with the
+// quotes nested in them. The text between quotes, an answer to each, is
+// kept. A quote which is not closed runs to the end of the message.
+func stripQuotes(html string) string {
+	var b strings.Builder
+	depth, start := 0, 0
+	for _, m := range blockquoteTag.FindAllStringSubmatchIndex(html, -1) {
+		closing := m[3] > m[2]
+		switch {
+		case !closing && depth == 0:
+			b.WriteString(html[start:m[0]])
+			depth++
+		case !closing:
+			depth++
+		case depth > 0:
+			depth--
+			if depth == 0 {
+				start = m[1]
+			}
+		}
+	}
+	if depth == 0 {
+		b.WriteString(html[start:])
+	}
+
+	return b.String()
+}
+
+// multipartText returns the text of the parts of a multipart entity: the
+// best of alternative parts, or the text of all the other parts.
+func multipartText(mediaType, boundary string, body io.Reader, depth int) (string, error) {
+	if boundary == "" {
+		return "", errors.Errorf("no boundary for the %s part", mediaType)
+	}
+
+	var texts []string
+	var best, bestRank int
+	mr := multipart.NewReader(body, boundary)
+	for {
+		p, err := mr.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", errors.WithMessagef(err, "bad %s part", mediaType)
+		}
+		text, err := entityText(p.Header, p, depth+1)
+		if err != nil {
+			return "", err
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		// plain text is what the sender wrote, HTML is often generated
+		rank := 1
+		if t, _, _ := mime.ParseMediaType(p.Header.Get("Content-Type")); t == "text/html" {
+			rank = 0
+		}
+		if len(texts) == 0 || rank > bestRank {
+			best, bestRank = len(texts), rank
+		}
+		texts = append(texts, text)
+	}
+
+	if len(texts) == 0 {
+		return "", nil
+	}
+	if mediaType == "multipart/alternative" {
+		return texts[best], nil
+	}
+
+	return strings.Join(texts, "\n\n"), nil
+}
+
+// transferDecoder decodes a body in the given Content-Transfer-Encoding.
+func transferDecoder(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &base64Cleaner{r: body})
+	}
+
+	// 7bit, 8bit and binary bodies are not encoded
+	return body
+}
+
+// base64Cleaner drops the whitespace of a base64 body, base64.Decoder
+// only skipping line breaks.
+type base64Cleaner struct {
+	r io.Reader
+}
+
+func (c *base64Cleaner) Read(p []byte) (int, error) {
+	for {
+		n, err := c.r.Read(p)
+		kept := 0
+		for _, b := range p[:n] {
+			if b != ' ' && b != '\t' && b != '\r' && b != '\n' {
+				p[kept] = b
+				kept++
+			}
+		}
+		if kept > 0 || err != nil {
+			return kept, err
+		}
+	}
+}
+
+// unflow joins the lines of format=flowed text (RFC 3676) broken by the
+// sender's mail client, keeping the lines broken by the sender.
+func unflow(text string, delSp bool) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	var b strings.Builder
+	for i, line := range lines {
+		// space stuffing protects lines starting with a space or "From "
+		line = strings.TrimPrefix(line, " ")
+		soft := strings.HasSuffix(line, " ") && line != "-- " && !strings.HasPrefix(line, ">")
+		if soft && delSp {
+			line = line[:len(line)-1]
+		}
+		b.WriteString(line)
+		if !soft && i < len(lines)-1 {
+			b.WriteByte('\n')
+		}
+	}
+
+	return b.String()
+}
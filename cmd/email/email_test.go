@@ -0,0 +1,185 @@
+package email
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    Message
+	}{
+		{
+			name: "plain text",
+			message: "From: Ann Smith <ann@example.com>\r\n" +
+				"Subject: Quarterly numbers\r\n" +
+				"Date: Mon, 2 Nov 2020 10:00:00 +0100\r\n" +
+				"\r\n" +
+				"Sales grew in every region.\r\n" +
+				"\r\n" +
+				"-- \r\n" +
+				"Ann Smith, Finance\r\n",
+			want: Message{
+				Subject: "Quarterly numbers",
+				From:    "Ann Smith <ann@example.com>",
+				Date:    time.Date(2020, 11, 2, 9, 0, 0, 0, time.UTC),
+				Text:    "Sales grew in every region.",
+			},
+		},
+		{
+			name: "encoded words and latin1 quoted-printable",
+			message: "From: =?iso-8859-1?q?Ren=E9e?= <renee@example.com>\r\n" +
+				"Subject: =?utf-8?b?UsOpc3Vtw6k=?=\r\n" +
+				"Content-Type: text/plain; charset=iso-8859-1\r\n" +
+				"Content-Transfer-Encoding: quoted-printable\r\n" +
+				"\r\n" +
+				"Le caf=E9 co=FBte 2 =80.\r\n",
+			want: Message{
+				Subject: "Résumé",
+				From:    "Renée <renee@example.com>",
+				Text:    "Le café coûte 2 €.",
+			},
+		},
+		{
+			name: "unencoded latin1 header",
+			message: "From: bob@example.com\r\n" +
+				"Subject: Caf\xe9\r\n" +
+				"\r\n" +
+				"Text.\r\n",
+			want: Message{Subject: "Café", From: "bob@example.com", Text: "Text."},
+		},
+		{
+			name: "koi8-r base64",
+			message: "From: ivan@example.com\r\n" +
+				"Content-Type: text/plain; charset=koi8-r\r\n" +
+				"Content-Transfer-Encoding: base64\r\n" +
+				"\r\n" +
+				"8NLJ18XU\r\n",
+			want: Message{From: "ivan@example.com", Text: "Привет"},
+		},
+		{
+			name: "utf-16 with a byte order mark",
+			message: "From: li@example.com\r\n" +
+				"Content-Type: text/plain; charset=utf-16\r\n" +
+				"Content-Transfer-Encoding: base64\r\n" +
+				"\r\n" +
+				"//4pWRRs\r\n",
+			want: Message{From: "li@example.com", Text: "天气"},
+		},
+		{
+			name: "utf-16 big endian by default",
+			message: "From: li@example.com\r\n" +
+				"Content-Type: text/plain; charset=utf-16\r\n" +
+				"Content-Transfer-Encoding: base64\r\n" +
+				"\r\n" +
+				"WSlsFA==\r\n",
+			want: Message{From: "li@example.com", Text: "天气"},
+		},
+		{
+			name: "flowed text",
+			message: "From: bob@example.com\r\n" +
+				"Content-Type: text/plain; format=flowed\r\n" +
+				"\r\n" +
+				"A line broken by the \r\n" +
+				"mail client.\r\n" +
+				"A line of its own.\r\n",
+			want: Message{From: "bob@example.com", Text: "A line broken by the mail client.\nA line of its own."},
+		},
+		{
+			name: "alternative parts and an attachment",
+			message: "From: bob@example.com\r\n" +
+				"Content-Type: multipart/mixed; boundary=outer\r\n" +
+				"\r\n" +
+				"--outer\r\n" +
+				"Content-Type: multipart/alternative; boundary=inner\r\n" +
+				"\r\n" +
+				"--inner\r\n" +
+				"Content-Type: text/html\r\n" +
+				"\r\n" +
+				"<p>The <b>HTML</b> version.</p>\r\n" +
+				"--inner\r\n" +
+				"Content-Type: text/plain\r\n" +
+				"\r\n" +
+				"The plain version.\r\n" +
+				"--inner--\r\n" +
+				"--outer\r\n" +
+				"Content-Type: text/plain\r\n" +
+				"Content-Disposition: attachment; filename=notes.txt\r\n" +
+				"\r\n" +
+				"Attached notes.\r\n" +
+				"--outer--\r\n",
+			want: Message{From: "bob@example.com", Text: "The plain version."},
+		},
+		{
+			name: "html with nested and separate quotes",
+			message: "From: bob@example.com\r\n" +
+				"Content-Type: text/html; charset=utf-8\r\n" +
+				"\r\n" +
+				"<p>First answer.</p>" +
+				"<blockquote><p>First question.</p><blockquote><p>Older.</p></blockquote><p>Still quoted.</p></blockquote>" +
+				"<p>Second answer.</p>" +
+				"<BLOCKQUOTE type=\"cite\"><p>Second question.</p></BLOCKQUOTE>" +
+				"<p>Thanks.</p>\r\n",
+			want: Message{From: "bob@example.com", Text: "First answer.\n\nSecond answer.\n\nThanks."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := Parse([]byte(tt.message))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if msg.Subject != tt.want.Subject || msg.From != tt.want.From || msg.Text != tt.want.Text {
+				t.Errorf("Parse() = %q, %q, %q, want %q, %q, %q", msg.Subject, msg.From, msg.Text, tt.want.Subject, tt.want.From, tt.want.Text)
+			}
+			if !msg.Date.Equal(tt.want.Date) {
+				t.Errorf("Parse() date = %v, want %v", msg.Date, tt.want.Date)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{"no header", "not a message", "malformed message"},
+		{"unknown charset", "Content-Type: text/plain; charset=x-unknown\r\n\r\nText\r\n", `unsupported charset "x-unknown"`},
+		{"no boundary", "Content-Type: multipart/mixed\r\n\r\nText\r\n", "no boundary"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.message))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripQuotes(t *testing.T) {
+	tests := []struct {
+		html string
+		want string
+	}{
+		{"<p>Answer</p>", "<p>Answer</p>"},
+		{"A<blockquote>q</blockquote>B<blockquote>r</blockquote>C", "ABC"},
+		{"A<blockquote>q<blockquote>r</blockquote>s</blockquote>B", "AB"},
+		{"A<blockquote>never closed", "A"},
+		{"A</blockquote>B", "A</blockquote>B"},
+		{"A<blockquotes>B", "A<blockquotes>B"},
+	}
+
+	for _, tt := range tests {
+		if got := stripQuotes(tt.html); got != tt.want {
+			t.Errorf("stripQuotes(%q) = %q, want %q", tt.html, got, tt.want)
+		}
+	}
+}
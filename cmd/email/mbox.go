@@ -0,0 +1,85 @@
+package email
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// MaxMessageSize bounds the size of a message read from an mbox file.
+const MaxMessageSize = 64 * 1024 * 1024
+
+// ErrTooLarge is returned by MboxReader.Next for a message larger than
+// MaxMessageSize, the reader moving on to the next message.
+var ErrTooLarge = errors.Errorf("message larger than %d MiB", MaxMessageSize/1024/1024)
+
+// MboxReader reads the messages of an mbox file one at a time.
+type MboxReader struct {
+	r *bufio.Reader
+	// inMessage tells the "From " line of a message was read
+	inMessage bool
+	// blank tells the previous line was empty, "From " lines only start
+	// a message after an empty line
+	blank bool
+}
+
+// NewMboxReader returns a reader of the messages of an mbox file.
+func NewMboxReader(r io.Reader) *MboxReader {
+	return &MboxReader{r: bufio.NewReader(r), blank: true}
+}
+
+// Next returns the next message, without its "From " line and with the
+// ">From " lines escaped by the mbox format unescaped. It returns io.EOF
+// after the last message.
+func (m *MboxReader) Next() ([]byte, error) {
+	var msg bytes.Buffer
+	tooLarge := false
+	end := func() ([]byte, error) {
+		if tooLarge {
+			return nil, ErrTooLarge
+		}
+		return bytes.TrimSuffix(msg.Bytes(), []byte("\n")), nil
+	}
+
+	for {
+		line, err := m.r.ReadBytes('\n')
+		if len(line) == 0 && err != nil {
+			if err == io.EOF && m.inMessage {
+				m.inMessage = false
+				return end()
+			}
+			return nil, err
+		}
+
+		content := bytes.TrimRight(line, "\r\n")
+		if m.blank && bytes.HasPrefix(content, []byte("From ")) {
+			m.blank = false
+			if m.inMessage {
+				return end()
+			}
+			m.inMessage = true
+			continue
+		}
+		m.blank = len(content) == 0
+		if !m.inMessage {
+			// only blank lines may come before the first "From " line
+			if len(bytes.TrimSpace(content)) != 0 {
+				return nil, errors.New("not an mbox file, it does not start with a \"From \" line")
+			}
+			continue
+		}
+
+		// mboxrd escapes ">From " as ">>From "
+		if unquoted := bytes.TrimLeft(line, ">"); len(unquoted) < len(line) && bytes.HasPrefix(unquoted, []byte("From ")) {
+			line = line[1:]
+		}
+		if tooLarge || msg.Len()+len(line) > MaxMessageSize {
+			tooLarge = true
+			msg.Reset()
+			continue
+		}
+		msg.Write(line)
+	}
+}
@@ -0,0 +1,47 @@
+package email
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestMboxReader(t *testing.T) {
+	mbox := "From ann@example.com Mon Nov  2 10:00:00 2020\n" +
+		"Subject: First\n" +
+		"\n" +
+		">From the start, quoted by mboxrd.\n" +
+		">>From a quote.\n" +
+		"From inside a paragraph is no separator.\n" +
+		"\n" +
+		"From bob@example.com Mon Nov  2 11:00:00 2020\n" +
+		"Subject: Second\n" +
+		"\n" +
+		"Body.\n"
+
+	want := []string{
+		"Subject: First\n\nFrom the start, quoted by mboxrd.\n>From a quote.\nFrom inside a paragraph is no separator.\n",
+		"Subject: Second\n\nBody.",
+	}
+
+	r := NewMboxReader(strings.NewReader(mbox))
+	for i, w := range want {
+		msg, err := r.Next()
+		if err != nil {
+			t.Fatalf("Next() %d error = %v", i, err)
+		}
+		if string(msg) != w {
+			t.Errorf("Next() %d = %q, want %q", i, msg, w)
+		}
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("Next() after the last message error = %v, want io.EOF", err)
+	}
+}
+
+func TestMboxReaderNotMbox(t *testing.T) {
+	r := NewMboxReader(strings.NewReader("Subject: no From line\n\nBody\n"))
+	if _, err := r.Next(); err == nil || !strings.Contains(err.Error(), "not an mbox file") {
+		t.Errorf("Next() error = %v, want not an mbox file", err)
+	}
+}
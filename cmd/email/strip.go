@@ -0,0 +1,91 @@
+package email
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// attribution lines introducing a quoted reply, e.g. "On Mon, 2 Nov
+	// 2020 at 10:00, Ann <ann@example.com> wrote:", in English, German,
+	// French, Spanish, Italian and Dutch
+	attribution = regexp.MustCompile(`(?i)^(on|am|le|el|il|op)\s.*(wrote|schrieb|a écrit|escribió|ha scritto|schreef)\s*:$`)
+
+	// separators above a reply quoted by Outlook and similar clients,
+	// the rest of the message being the quoted reply
+	replySeparator = regexp.MustCompile(`(?i)^(-{2,}\s*(original message|ursprüngliche nachricht|message d'origine|mensaje original)\s*-{2,}|_{10,})$`)
+
+	// sign-offs of mobile mail clients, the rest of the message being
+	// quoted or empty
+	mobileSignature = regexp.MustCompile(`(?i)^(sent from my \w+|von meinem \w+ gesendet|envoyé de mon \w+|enviado desde mi \w+)`)
+
+	// quoted reply headers of Outlook, "From: Ann" followed by "Sent:"
+	// or "Date:"
+	headerFrom = regexp.MustCompile(`(?i)^\*?(from|von|de):\*?\s`)
+	headerDate = regexp.MustCompile(`(?i)^\*?(sent|date|gesendet|envoyé|enviado|datum):\*?\s`)
+)
+
+// Strip removes the quoted replies and the signature of the text of a
+// message, along with the blank lines left over.
+func Strip(text string) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	var kept []string
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t")
+		trimmed := strings.TrimSpace(line)
+
+		// "-- " is the signature delimiter, a bare "--" being as likely a
+		// dash of the text
+		if lines[i] == "-- " || replySeparator.MatchString(trimmed) || mobileSignature.MatchString(trimmed) {
+			break
+		}
+		if headerFrom.MatchString(trimmed) && quotedHeaders(lines[i+1:]) {
+			break
+		}
+		if strings.HasPrefix(trimmed, ">") {
+			continue
+		}
+		if attribution.MatchString(trimmed) {
+			continue
+		}
+		// long attributions are wrapped onto a second line
+		if i+1 < len(lines) && attribution.MatchString(trimmed+" "+strings.TrimSpace(lines[i+1])) {
+			i++
+			continue
+		}
+
+		kept = append(kept, line)
+	}
+
+	// collapse the blank lines where quotes were taken out
+	var b strings.Builder
+	blank := false
+	for _, line := range kept {
+		if strings.TrimSpace(line) == "" {
+			blank = b.Len() != 0
+			continue
+		}
+		if blank {
+			b.WriteString("\n\n")
+		} else if b.Len() != 0 {
+			b.WriteByte('\n')
+		}
+		blank = false
+		b.WriteString(line)
+	}
+
+	return b.String()
+}
+
+// quotedHeaders tells whether the lines following a "From:" line are the
+// headers of a quoted message.
+func quotedHeaders(lines []string) bool {
+	for i := 0; i < len(lines) && i < 4; i++ {
+		if headerDate.MatchString(strings.TrimSpace(lines[i])) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,65 @@
+package email
+
+import "testing"
+
+func TestStrip(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "signature",
+			text: "See you tomorrow.\n\n-- \nAnn Smith\nFinance",
+			want: "See you tomorrow.",
+		},
+		{
+			name: "bare dashes are text",
+			text: "Options:\n--\nverbose or quiet",
+			want: "Options:\n--\nverbose or quiet",
+		},
+		{
+			name: "quoted reply",
+			text: "Yes, Friday works.\n\nOn Mon, 2 Nov 2020 at 10:00, Ann <ann@example.com> wrote:\n> Does Friday work?\n> Ann",
+			want: "Yes, Friday works.",
+		},
+		{
+			name: "wrapped attribution",
+			text: "Agreed.\n\nOn Mon, 2 Nov 2020 at 10:00, Ann Smith from the finance team\n<ann@example.com> wrote:\n> Shall we?",
+			want: "Agreed.",
+		},
+		{
+			name: "inline answers",
+			text: "> First question?\nFirst answer.\n\n> Second question?\nSecond answer.",
+			want: "First answer.\n\nSecond answer.",
+		},
+		{
+			name: "outlook reply",
+			text: "Thanks, done.\n\nFrom: Ann Smith\nSent: Monday, November 2, 2020 10:00\nTo: Bob\nSubject: Report\n\nPlease send the report.",
+			want: "Thanks, done.",
+		},
+		{
+			name: "original message separator",
+			text: "Forwarding this.\n-----Original Message-----\nOld text.",
+			want: "Forwarding this.",
+		},
+		{
+			name: "mobile signature",
+			text: "On my way.\n\nSent from my iPhone",
+			want: "On my way.",
+		},
+		{
+			name: "from in the text",
+			text: "From: the sales team, with thanks.\nSee the attached report.",
+			want: "From: the sales team, with thanks.\nSee the attached report.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Strip(tt.text); got != tt.want {
+				t.Errorf("Strip() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
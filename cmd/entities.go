@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	api "github.com/repustate/rcli/api-client/v4"
+	"github.com/repustate/rcli/cmd/query"
+)
+
+const (
+	entitiesFilename = "entities.json"
+	// maximum number of entity titles remembered per classification
+	maxTitlesPerClass = 500
+)
+
+// knownEntities maps classifications to titles of entities the server
+// returned for them, used to complete "Class=value" query terms.
+type knownEntities map[string][]string
+
+func entitiesPath() string {
+	return filepath.Join(getConfigDir(), entitiesFilename)
+}
+
+func loadKnownEntities() knownEntities {
+	known := knownEntities{}
+
+	data, err := ioutil.ReadFile(entitiesPath())
+	if err != nil {
+		return known
+	}
+	// a damaged file only costs completions, it is rewritten on next store
+	json.Unmarshal(data, &known)
+
+	return known
+}
+
+// rememberEntities adds titles of entities to the known entities file.
+// Failures are ignored, the file only serves completions.
+func rememberEntities(entities []api.Entity) {
+	if len(entities) == 0 {
+		return
+	}
+
+	known := loadKnownEntities()
+	changed := false
+	for _, e := range entities {
+		if e.Title == "" {
+			continue
+		}
+		for _, c := range e.Classifications {
+			if known.add(c, e.Title) {
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return
+	}
+
+	data, err := json.Marshal(known)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(getConfigDir(), 0700); err != nil {
+		return
+	}
+	ioutil.WriteFile(entitiesPath(), data, 0600)
+}
+
+func (k knownEntities) add(class, title string) bool {
+	titles := k[class]
+	i := sort.SearchStrings(titles, title)
+	if i < len(titles) && titles[i] == title {
+		return false
+	}
+	if len(titles) >= maxTitlesPerClass {
+		return false
+	}
+
+	titles = append(titles, "")
+	copy(titles[i+1:], titles[i:])
+	titles[i] = title
+	k[class] = titles
+
+	return true
+}
+
+// completeClassValue completes a "Class=value" term from entity titles seen
+// before. It reports false when toComplete does not name a classification.
+func completeClassValue(toComplete string) ([]string, bool) {
+	class, value, ok := query.SplitClassValue(toComplete)
+	if !ok {
+		if !query.HasClass(toComplete) {
+			return nil, false
+		}
+		class = toComplete
+	}
+	if !query.HasClass(class) {
+		return nil, false
+	}
+
+	value = strings.TrimPrefix(value, `"`)
+	var completions []string
+	for _, title := range loadKnownEntities()[class] {
+		if strings.HasPrefix(strings.ToLower(title), strings.ToLower(value)) {
+			completions = append(completions, class+"="+query.QuoteValue(title))
+		}
+	}
+
+	return completions, true
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	api "github.com/repustate/rcli/api-client/v4"
+)
+
+const (
+	// demoIndexTTL is how long the public demo server keeps an index
+	demoIndexTTL = 24 * time.Hour
+
+	// expiryWarning is how long before the expiry searches warn about it
+	expiryWarning = 2 * time.Hour
+)
+
+// indexExpiry is the lifetime of an index on a server deleting indexes
+// some time after their first document.
+type indexExpiry struct {
+	// First is zero when nothing was indexed through this rcli
+	First time.Time
+	TTL   time.Duration
+}
+
+func loadIndexExpiry(user string, ttl time.Duration) (indexExpiry, error) {
+	ids, err := loadIdentities()
+	if err != nil {
+		return indexExpiry{}, err
+	}
+
+	return indexExpiry{First: ids.FirstIndexed[user], TTL: ttl}, nil
+}
+
+// known reports whether the index has a known expiry time.
+func (e indexExpiry) known() bool {
+	return e.TTL > 0 && !e.First.IsZero()
+}
+
+func (e indexExpiry) expiresAt() time.Time {
+	return e.First.Add(e.TTL)
+}
+
+func (e indexExpiry) expired(now time.Time) bool {
+	return e.known() && !now.Before(e.expiresAt())
+}
+
+// noteIndexed records that a document was indexed for user at t, starting
+// the lifetime of its index unless it already started.
+func noteIndexed(user string, ttl time.Duration, t time.Time) error {
+	ids, err := loadIdentities()
+	if err != nil {
+		return err
+	}
+
+	e := indexExpiry{First: ids.FirstIndexed[user], TTL: ttl}
+	if !e.First.IsZero() && !e.expired(t) {
+		return nil
+	}
+	if ids.FirstIndexed == nil {
+		ids.FirstIndexed = map[string]time.Time{}
+	}
+	ids.FirstIndexed[user] = t.UTC().Truncate(time.Second)
+
+	return storeIdentities(ids)
+}
+
+// newExpiryTrackingService records when each identity first indexed
+// a document through next.
+func newExpiryTrackingService(next api.Service, ttl time.Duration) api.Service {
+	return &expiryTrackingService{Service: next, ttl: ttl, noted: map[string]bool{}}
+}
+
+type expiryTrackingService struct {
+	api.Service
+	ttl time.Duration
+
+	mu sync.Mutex
+	// users whose index is known to have started during this run
+	noted map[string]bool
+}
+
+func (s *expiryTrackingService) IndexContext(ctx context.Context, text, lang, user string, opts ...api.IndexOption) (*api.IndexResult, error) {
+	res, err := s.Service.IndexContext(ctx, text, lang, user, opts...)
+	if err == nil {
+		s.note(user)
+	}
+
+	return res, err
+}
+
+func (s *expiryTrackingService) note(user string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.noted[user] {
+		return
+	}
+	if err := noteIndexed(user, s.ttl, time.Now()); err != nil {
+		printErr(fmt.Sprintf("Failed to record the index lifetime: %v", err))
+	}
+	s.noted[user] = true
+}
+
+// warnExpiry tells when the index of user is about to expire or expired,
+// so empty search results are not mistaken for missing documents.
+func warnExpiry(p profile, user string) {
+	e, err := loadIndexExpiry(user, p.IndexTTL)
+	if err != nil || !e.known() {
+		return
+	}
+
+	now := time.Now()
+	switch left := e.expiresAt().Sub(now); {
+	case e.expired(now):
+		printErr(fmt.Sprintf("Your index expired %s ago, documents indexed before %s were deleted by the server.",
+			formatDuration(-left), e.expiresAt().Local().Format(timeFormat)))
+		printReseedHint(user)
+	case left < expiryWarning:
+		printErr(fmt.Sprintf("Your index expires in %s, at %s.", formatDuration(left), e.expiresAt().Local().Format(timeFormat)))
+	}
+}
+
+// printReseedHint suggests how to get the documents of an expired index back.
+func printReseedHint(user string) {
+	n, err := countMirror(user)
+	switch {
+	case err != nil:
+	case n != 0:
+		printMsg(fmt.Sprintf("Run `rcli reindex` to index the %d documents of the local mirror again.", n))
+	default:
+		printMsg("Run `rcli config set mirror true` to keep a local copy of documents you index from now on.")
+	}
+}
+
+const timeFormat = "2006-01-02 15:04 MST"
+
+// formatDuration rounds d to minutes, e.g. 3h12m.
+func formatDuration(d time.Duration) string {
+	if d < time.Minute {
+		return "less than a minute"
+	}
+	s := strings.TrimSuffix(d.Round(time.Minute).String(), "0s")
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
@@ -0,0 +1,88 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"net/url"
+	"path"
+
+	"github.com/pkg/errors"
+)
+
+// EPUB returns the text of the chapters of an e-book, in reading order.
+func EPUB(data []byte) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", errors.WithMessage(err, "bad EPUB document")
+	}
+
+	// the container names the package document listing the chapters
+	container, err := readZipEntry(zr, "META-INF/container.xml")
+	if err != nil {
+		return "", err
+	}
+	var c struct {
+		Rootfiles []struct {
+			Path string `xml:"full-path,attr"`
+		} `xml:"rootfiles>rootfile"`
+	}
+	if err := xml.Unmarshal(container, &c); err != nil {
+		return "", errors.WithMessage(err, "bad EPUB container")
+	}
+	if len(c.Rootfiles) == 0 {
+		return "", errors.New("no package document in the EPUB container")
+	}
+
+	opfPath := c.Rootfiles[0].Path
+	opf, err := readZipEntry(zr, opfPath)
+	if err != nil {
+		return "", err
+	}
+	var pkg struct {
+		Items []struct {
+			ID   string `xml:"id,attr"`
+			Href string `xml:"href,attr"`
+			Type string `xml:"media-type,attr"`
+		} `xml:"manifest>item"`
+		Spine []struct {
+			IDRef  string `xml:"idref,attr"`
+			Linear string `xml:"linear,attr"`
+		} `xml:"spine>itemref"`
+	}
+	if err := xml.Unmarshal(opf, &pkg); err != nil {
+		return "", errors.WithMessage(err, "bad EPUB package document")
+	}
+
+	hrefs := map[string]string{}
+	for _, item := range pkg.Items {
+		if item.Type == "application/xhtml+xml" || item.Type == TypeHTML {
+			hrefs[item.ID] = item.Href
+		}
+	}
+
+	var b bytes.Buffer
+	for _, ref := range pkg.Spine {
+		href, ok := hrefs[ref.IDRef]
+		if !ok || ref.Linear == "no" {
+			continue
+		}
+		if unescaped, err := url.PathUnescape(href); err == nil {
+			href = unescaped
+		}
+		chapter, err := readZipEntry(zr, path.Join(path.Dir(opfPath), href))
+		if err != nil {
+			return "", err
+		}
+		text, err := HTML(chapter)
+		if err != nil {
+			return "", errors.WithMessagef(err, "bad chapter %s", href)
+		}
+		if b.Len() != 0 && text != "" {
+			b.WriteString("\n\n")
+		}
+		b.WriteString(text)
+	}
+
+	return b.String(), nil
+}
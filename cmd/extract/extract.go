@@ -0,0 +1,264 @@
+// Package extract turns documents such as web pages, PDFs and word
+// processor files into the plain text the Repustate API expects.
+//
+// Extractors are registered per MIME type along with the file extensions
+// of the type. The type of a file is told by its extension, or sniffed
+// from its content when the extension is unknown:
+//
+//	text, mimeType, err := extract.Text("report.pdf", data)
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// MIME types of the formats extracted by this package.
+const (
+	TypePlain    = "text/plain"
+	TypeHTML     = "text/html"
+	TypeMarkdown = "text/markdown"
+	TypePDF      = "application/pdf"
+	TypeDOCX     = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	TypeODT      = "application/vnd.oasis.opendocument.text"
+	TypeEPUB     = "application/epub+zip"
+)
+
+// UnsupportedError is returned for binary files no extractor is
+// registered for.
+type UnsupportedError struct {
+	// Type is the MIME type of the file.
+	Type string
+}
+
+func (e *UnsupportedError) Error() string {
+	return "unsupported file type " + e.Type
+}
+
+// Func returns the plain text of a document with the given content.
+type Func func(data []byte) (string, error)
+
+var (
+	mu         sync.RWMutex
+	extractors = map[string]Func{}
+	extensions = map[string]string{}
+)
+
+func init() {
+	Register(TypePlain, plainText, ".txt", ".text")
+	Register(TypeHTML, HTML, ".html", ".htm", ".xhtml")
+	Register(TypeMarkdown, Markdown, ".md", ".markdown")
+	Register(TypePDF, PDF, ".pdf")
+	Register(TypeDOCX, DOCX, ".docx")
+	Register(TypeODT, ODT, ".odt")
+	Register(TypeEPUB, EPUB, ".epub")
+}
+
+// Register makes f the extractor of documents of the given MIME type,
+// files with one of the extensions being of that type. Extractors
+// registered before for the type or the extensions are replaced.
+func Register(mimeType string, f Func, exts ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	extractors[mimeType] = f
+	for _, ext := range exts {
+		extensions[strings.ToLower(ext)] = mimeType
+	}
+}
+
+// TypeOf returns the MIME type of the named file with content data, by
+// its extension when it is registered or else by sniffing data.
+func TypeOf(name string, data []byte) string {
+	mu.RLock()
+	mimeType, ok := extensions[strings.ToLower(filepath.Ext(name))]
+	mu.RUnlock()
+	if ok {
+		return mimeType
+	}
+
+	return sniff(data)
+}
+
+// Text returns the plain text of the named file with content data, along
+// with its MIME type. Text files of a type without extractor are returned
+// as they are, other files fail with an UnsupportedError.
+func Text(name string, data []byte) (string, string, error) {
+	mimeType := TypeOf(name, data)
+
+	mu.RLock()
+	f, ok := extractors[mimeType]
+	mu.RUnlock()
+	if !ok {
+		if !strings.HasPrefix(mimeType, "text/") {
+			return "", mimeType, &UnsupportedError{Type: mimeType}
+		}
+		f = plainText
+	}
+
+	text, err := f(data)
+	if err != nil {
+		return "", mimeType, err
+	}
+	if strings.TrimSpace(text) == "" {
+		return "", mimeType, errors.Errorf("no text found in %s document", mimeType)
+	}
+
+	return text, mimeType, nil
+}
+
+// sniff tells the MIME type of data from its content.
+func sniff(data []byte) string {
+	mimeType := http.DetectContentType(data)
+	if i := strings.Index(mimeType, ";"); i >= 0 {
+		mimeType = mimeType[:i]
+	}
+
+	switch {
+	case mimeType == "application/zip":
+		return sniffZip(data)
+	case mimeType == "text/xml" && bytes.Contains(data[:min(len(data), 512)], []byte("<html")):
+		return TypeHTML
+	case mimeType == "application/octet-stream" && utf8.Valid(data) && !bytes.ContainsRune(data, 0):
+		// control characters other than NUL make text sniff as binary
+		return TypePlain
+	}
+
+	return mimeType
+}
+
+// sniffZip tells the type of the documents stored as zip archives.
+func sniffZip(data []byte) string {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "application/zip"
+	}
+
+	for _, f := range zr.File {
+		switch f.Name {
+		case "mimetype":
+			// OpenDocument and EPUB files start with their type
+			if rc, err := f.Open(); err == nil {
+				buf := make([]byte, 128)
+				n, _ := rc.Read(buf)
+				rc.Close()
+				return strings.TrimSpace(string(buf[:n]))
+			}
+		case "word/document.xml":
+			return TypeDOCX
+		}
+	}
+
+	return "application/zip"
+}
+
+func plainText(data []byte) (string, error) {
+	// a byte order mark is not part of the text
+	return strings.TrimPrefix(string(data), "\ufeff"), nil
+}
+
+// decodeLatin1 converts text in ISO 8859-1, or the Windows code page 1252
+// mostly matching it, to UTF-8.
+func decodeLatin1(data []byte) string {
+	var b strings.Builder
+	b.Grow(len(data))
+	for _, c := range data {
+		if r, ok := cp1252[c]; ok {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune(rune(c))
+		}
+	}
+
+	return b.String()
+}
+
+// cp1252 are the characters of code page 1252 which differ from ISO 8859-1
+var cp1252 = map[byte]rune{
+	0x80: '€', 0x82: '‚', 0x83: 'ƒ', 0x84: '„', 0x85: '…', 0x86: '†', 0x87: '‡',
+	0x88: 'ˆ', 0x89: '‰', 0x8a: 'Š', 0x8b: '‹', 0x8c: 'Œ', 0x8e: 'Ž',
+	0x91: '‘', 0x92: '’', 0x93: '“', 0x94: '”', 0x95: '•', 0x96: '–', 0x97: '—',
+	0x98: '˜', 0x99: '™', 0x9a: 'š', 0x9b: '›', 0x9c: 'œ', 0x9e: 'ž', 0x9f: 'Ÿ',
+}
+
+// textBuilder assembles extracted text, collapsing the whitespace of the
+// markup and separating paragraphs with blank lines.
+type textBuilder struct {
+	b strings.Builder
+	// pending whitespace: a space, or the number of line breaks
+	space  bool
+	breaks int
+}
+
+// write adds text, collapsing its whitespace.
+func (t *textBuilder) write(s string) {
+	if s == "" {
+		return
+	}
+	if r, _ := utf8.DecodeRuneInString(s); unicode.IsSpace(r) {
+		t.space = true
+	}
+	for i, word := range strings.Fields(s) {
+		if i != 0 {
+			t.space = true
+		}
+		t.flush()
+		t.b.WriteString(word)
+	}
+	if r, _ := utf8.DecodeLastRuneInString(s); unicode.IsSpace(r) {
+		t.space = true
+	}
+}
+
+// writeRaw adds preformatted text as it is.
+func (t *textBuilder) writeRaw(s string) {
+	if s == "" {
+		return
+	}
+	t.flush()
+	t.b.WriteString(s)
+}
+
+// line ends the current line.
+func (t *textBuilder) line() {
+	if t.b.Len() != 0 && t.breaks < 1 {
+		t.breaks = 1
+	}
+}
+
+// paragraph ends the current paragraph.
+func (t *textBuilder) paragraph() {
+	if t.b.Len() != 0 {
+		t.breaks = 2
+	}
+}
+
+func (t *textBuilder) flush() {
+	switch {
+	case t.breaks != 0:
+		t.b.WriteString(strings.Repeat("\n", t.breaks))
+	case t.space && t.b.Len() != 0:
+		t.b.WriteByte(' ')
+	}
+	t.breaks = 0
+	t.space = false
+}
+
+func (t *textBuilder) String() string {
+	return t.b.String()
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,84 @@
+package extract
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		file     string
+		mimeType string
+		want     string
+	}{
+		{"sample.html", TypeHTML, "Weather report\n\nThe weather in London is good.\n\nSunny\nWarm"},
+		{"sample.md", TypeMarkdown, "Release notes\n\nThe new exporter supports CSV and JSON files.\n\nFormat Status\n\nCSV done"},
+		{"sample.pdf", TypePDF, "Hello from page one.\nSecond line.\n\nKerned words"},
+		{"sample.docx", TypeDOCX, "Quarterly report\n\nSales grew."},
+		{"sample.odt", TypeODT, "Minutes\n\nThe board met on Monday."},
+		{"sample.epub", TypeEPUB, "Chapter two comes first.\n\nChapter one text."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := ioutil.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			text, mimeType, err := Text(tt.file, data)
+			if err != nil {
+				t.Fatalf("Text() error = %v", err)
+			}
+			if mimeType != tt.mimeType {
+				t.Errorf("Text() type = %q, want %q", mimeType, tt.mimeType)
+			}
+			if text != tt.want {
+				t.Errorf("Text() = %q, want %q", text, tt.want)
+			}
+
+			// the content tells the type of files without an extension
+			if got := TypeOf("upload", data); got != tt.mimeType && tt.mimeType != TypeMarkdown {
+				t.Errorf("TypeOf() without extension = %q, want %q", got, tt.mimeType)
+			}
+		})
+	}
+}
+
+func TestTextErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"image.png", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR", "unsupported file type image/png"},
+		{"empty.txt", "  \n\t", "no text found in text/plain document"},
+		{"empty.html", "<html><body><nav>menu</nav></body></html>", "no text found in text/html document"},
+		{"broken.docx", "PK\x03\x04 not really a zip", "bad DOCX document"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Text(tt.name, []byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Text() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	_, _, err := Text("image.png", []byte("\x89PNG\r\n\x1a\n"))
+	var unsupported *UnsupportedError
+	if !errors.As(err, &unsupported) {
+		t.Errorf("Text() error = %T, want *UnsupportedError", err)
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	text, mimeType, err := Text("notes.txt", []byte("\ufeffPlain notes."))
+	if err != nil || mimeType != TypePlain || text != "Plain notes." {
+		t.Errorf("Text() = %q, %q, %v", text, mimeType, err)
+	}
+}
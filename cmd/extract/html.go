@@ -0,0 +1,201 @@
+package extract
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// elements whose content is not text, removed before parsing as
+	// they may hold markup characters encoding/xml chokes on
+	rawElements = []*regexp.Regexp{
+		regexp.MustCompile(`(?is)<script\b.*?</script\s*>`),
+		regexp.MustCompile(`(?is)<style\b.*?</style\s*>`),
+		regexp.MustCompile(`(?is)<!\[CDATA\[.*?\]\]>`),
+	}
+
+	// elements holding page furniture rather than content
+	boilerplate = map[string]bool{
+		"aside":    true,
+		"button":   true,
+		"canvas":   true,
+		"footer":   true,
+		"form":     true,
+		"head":     true,
+		"header":   true,
+		"iframe":   true,
+		"menu":     true,
+		"nav":      true,
+		"noscript": true,
+		"object":   true,
+		"script":   true,
+		"select":   true,
+		"style":    true,
+		"svg":      true,
+		"template": true,
+	}
+
+	// elements holding the content of a page, when present
+	mainContent = map[string]bool{
+		"article": true,
+		"main":    true,
+	}
+
+	// elements starting a new paragraph
+	blocks = map[string]bool{
+		"address":    true,
+		"blockquote": true,
+		"dd":         true,
+		"div":        true,
+		"dl":         true,
+		"dt":         true,
+		"figcaption": true,
+		"figure":     true,
+		"h1":         true,
+		"h2":         true,
+		"h3":         true,
+		"h4":         true,
+		"h5":         true,
+		"h6":         true,
+		"hr":         true,
+		"ol":         true,
+		"p":          true,
+		"pre":        true,
+		"section":    true,
+		"table":      true,
+		"ul":         true,
+	}
+
+	// elements starting a new line
+	lines = map[string]bool{
+		"br": true,
+		"li": true,
+		"tr": true,
+	}
+)
+
+// HTML returns the text of a web page without its markup. Navigation,
+// headers, footers and other page furniture are left out, as is
+// everything outside the main content of pages marking it with <main>
+// or <article>.
+func HTML(data []byte) (string, error) {
+	if !utf8.Valid(data) {
+		data = []byte(decodeLatin1(data))
+	}
+	for _, re := range rawElements {
+		data = re.ReplaceAll(data, nil)
+	}
+
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+	// the content is UTF-8 by now, whatever the declaration says
+	d.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	var (
+		all, main, title textBuilder
+		stack            []string
+		skip, inMain     int
+		inTitle, pre     int
+	)
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// keep the text read up to a hopelessly broken part
+			if strings.TrimSpace(all.String()+title.String()) == "" {
+				return "", errors.WithMessage(err, "malformed HTML")
+			}
+			break
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			name := strings.ToLower(tok.Name.Local)
+			stack = append(stack, name)
+			switch {
+			case name == "title":
+				inTitle++
+			case boilerplate[name]:
+				skip++
+			case mainContent[name]:
+				inMain++
+			case name == "pre":
+				pre++
+			}
+			htmlBreak(name, &all, &main, inMain)
+		case xml.EndElement:
+			if len(stack) == 0 {
+				continue
+			}
+			name := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if name != "br" {
+				htmlBreak(name, &all, &main, inMain)
+			}
+			switch {
+			case name == "title":
+				inTitle--
+			case boilerplate[name]:
+				skip--
+			case mainContent[name]:
+				inMain--
+			case name == "pre":
+				pre--
+			}
+		case xml.CharData:
+			switch {
+			case inTitle > 0:
+				title.write(string(tok))
+			case skip > 0:
+			case pre > 0:
+				all.writeRaw(string(tok))
+				if inMain > 0 {
+					main.writeRaw(string(tok))
+				}
+			default:
+				all.write(string(tok))
+				if inMain > 0 {
+					main.write(string(tok))
+				}
+			}
+		}
+	}
+
+	if text := main.String(); strings.TrimSpace(text) != "" {
+		return text, nil
+	}
+	if text := all.String(); strings.TrimSpace(text) != "" {
+		return text, nil
+	}
+
+	return title.String(), nil
+}
+
+// htmlBreak separates the text around a block or line element.
+func htmlBreak(name string, all, main *textBuilder, inMain int) {
+	for _, t := range []*textBuilder{all, main} {
+		if t == main && inMain == 0 {
+			continue
+		}
+		switch {
+		case blocks[name] || mainContent[name]:
+			t.paragraph()
+		case lines[name]:
+			t.line()
+		case name == "td" || name == "th":
+			t.space = true
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package extract
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	mdFence     = regexp.MustCompile("^\\s{0,3}(```|~~~)")
+	mdHeading   = regexp.MustCompile(`^\s{0,3}#{1,6}\s+`)
+	mdClosing   = regexp.MustCompile(`\s+#+\s*$`)
+	mdSetext    = regexp.MustCompile(`^\s{0,3}(=+|-+)\s*$`)
+	mdRule      = regexp.MustCompile(`^\s{0,3}([-*_])(\s*[-*_]){2,}\s*$`)
+	mdQuote     = regexp.MustCompile(`^\s{0,3}(>\s?)+`)
+	mdListItem  = regexp.MustCompile(`^\s*([-*+]|\d{1,9}[.)])\s+(\[[ xX]\]\s+)?`)
+	mdRefDef    = regexp.MustCompile(`^\s{0,3}\[[^\]]+\]:\s+\S+`)
+	mdTableRule = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+	mdImage     = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	mdLink      = regexp.MustCompile(`\[([^\]]+)\](\([^)]*\)|\[[^\]]*\])`)
+	mdAutolink  = regexp.MustCompile(`<((https?|mailto):[^>\s]+)>`)
+	mdTag       = regexp.MustCompile(`</?[a-zA-Z][^>]*>`)
+	mdCode      = regexp.MustCompile("`+([^`]*)`+")
+	mdEmphasis  = regexp.MustCompile(`(\*{1,3}|~~)(\S(?:.*?\S)?)(\*{1,3}|~~)`)
+	// underscores within words, e.g. in snake_case, are not emphasis
+	mdUnderscore = regexp.MustCompile(`(^|\W)_{1,3}(\S(?:.*?\S)?)_{1,3}(\W|$)`)
+)
+
+// Markdown returns the text of a Markdown document without its markup.
+// Fenced code blocks and front matter are left out, links are replaced
+// by their text and images by their description.
+func Markdown(data []byte) (string, error) {
+	text, _ := plainText(data)
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	// YAML front matter of static site generators
+	if len(lines) != 0 && strings.TrimSpace(lines[0]) == "---" {
+		for i := 1; i < len(lines); i++ {
+			if s := strings.TrimSpace(lines[i]); s == "---" || s == "..." {
+				lines = lines[i+1:]
+				break
+			}
+		}
+	}
+
+	var t textBuilder
+	fence := ""
+	for _, line := range lines {
+		if m := mdFence.FindStringSubmatch(line); m != nil {
+			switch {
+			case fence == "":
+				fence = m[1]
+			case fence == m[1]:
+				fence = ""
+			}
+			t.paragraph()
+			continue
+		}
+		if fence != "" {
+			continue
+		}
+
+		switch {
+		case strings.TrimSpace(line) == "":
+			t.paragraph()
+			continue
+		case mdRule.MatchString(line), mdRefDef.MatchString(line), mdTableRule.MatchString(line) && strings.Contains(line, "-"):
+			t.paragraph()
+			continue
+		case mdSetext.MatchString(line):
+			// underline of the heading on the line before
+			t.paragraph()
+			continue
+		}
+
+		heading := mdHeading.MatchString(line)
+		if heading {
+			t.paragraph()
+			line = mdClosing.ReplaceAllString(mdHeading.ReplaceAllString(line, ""), "")
+		}
+		line = mdQuote.ReplaceAllString(line, "")
+		if mdListItem.MatchString(line) {
+			t.line()
+			line = mdListItem.ReplaceAllString(line, "")
+		}
+		if strings.Contains(line, "|") {
+			line = strings.Trim(strings.TrimSpace(line), "|")
+			line = strings.ReplaceAll(line, "|", " ")
+		}
+
+		t.write(markdownInline(line) + "\n")
+		if heading {
+			t.paragraph()
+		}
+	}
+
+	return t.String(), nil
+}
+
+// markdownInline strips the markup of the text of a line.
+func markdownInline(s string) string {
+	s = mdImage.ReplaceAllString(s, "$1")
+	s = mdLink.ReplaceAllString(s, "$1")
+	s = mdAutolink.ReplaceAllString(s, "$1")
+	s = mdTag.ReplaceAllString(s, "")
+	s = mdCode.ReplaceAllString(s, "$1")
+	for i := 0; i < 2; i++ {
+		s = mdEmphasis.ReplaceAllString(s, "$2")
+		s = mdUnderscore.ReplaceAllString(s, "$1$2$3")
+	}
+	s = strings.NewReplacer(`\*`, "*", `\_`, "_", `\#`, "#", `\[`, "[", `\]`, "]", `\\`, `\`, "&nbsp;", " ", "&amp;", "&").Replace(s)
+
+	return s
+}
@@ -0,0 +1,147 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+)
+
+// maxZipEntrySize bounds the size of a file read from a document archive,
+// against archives inflating to huge sizes
+const maxZipEntrySize = 256 * 1024 * 1024
+
+// DOCX returns the text of the body of a Word document.
+func DOCX(data []byte) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", errors.WithMessage(err, "bad DOCX document")
+	}
+	doc, err := readZipEntry(zr, "word/document.xml")
+	if err != nil {
+		return "", err
+	}
+
+	var t textBuilder
+	inText := 0
+	err = walkXML(doc, func(tok xml.Token) {
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			switch tok.Name.Local {
+			case "t":
+				inText++
+			case "tab":
+				t.space = true
+			case "br", "cr":
+				t.line()
+			}
+		case xml.EndElement:
+			switch tok.Name.Local {
+			case "t":
+				inText--
+			case "p":
+				t.paragraph()
+			case "tc":
+				t.space = true
+			}
+		case xml.CharData:
+			if inText > 0 {
+				t.write(string(tok))
+			}
+		}
+	})
+
+	return t.String(), err
+}
+
+// ODT returns the text of an OpenDocument text document.
+func ODT(data []byte) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", errors.WithMessage(err, "bad ODT document")
+	}
+	content, err := readZipEntry(zr, "content.xml")
+	if err != nil {
+		return "", err
+	}
+
+	var t textBuilder
+	inBody, skip := 0, 0
+	err = walkXML(content, func(tok xml.Token) {
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			switch tok.Name.Local {
+			case "body":
+				inBody++
+			case "annotation", "note-citation", "tracked-changes":
+				skip++
+			case "s", "tab":
+				t.space = true
+			case "line-break":
+				t.line()
+			}
+		case xml.EndElement:
+			switch tok.Name.Local {
+			case "body":
+				inBody--
+			case "annotation", "note-citation", "tracked-changes":
+				skip--
+			case "p", "h":
+				if skip == 0 {
+					t.paragraph()
+				}
+			case "table-cell":
+				t.space = true
+			}
+		case xml.CharData:
+			if inBody > 0 && skip == 0 {
+				t.write(string(tok))
+			}
+		}
+	})
+
+	return t.String(), err
+}
+
+// walkXML calls visit with every token of an XML document.
+func walkXML(data []byte, visit func(xml.Token)) error {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.WithMessage(err, "malformed XML")
+		}
+		visit(tok)
+	}
+}
+
+// readZipEntry returns the content of the named file of an archive.
+func readZipEntry(zr *zip.Reader, name string) ([]byte, error) {
+	for _, f := range zr.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+
+		data, err := ioutil.ReadAll(io.LimitReader(rc, maxZipEntrySize+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > maxZipEntrySize {
+			return nil, errors.Errorf("%s is larger than %d MiB", name, maxZipEntrySize/1024/1024)
+		}
+		return data, nil
+	}
+
+	return nil, errors.Errorf("no %s in the archive", name)
+}
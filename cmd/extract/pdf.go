@@ -0,0 +1,959 @@
+package extract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strconv"
+	"unicode/utf16"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// maxPDFDepth bounds the nesting of page trees, form XObjects and
+	// references followed while extracting a PDF
+	maxPDFDepth = 32
+	// maxPDFStreamSize bounds the decoded size of a stream, against
+	// streams inflating to huge sizes
+	maxPDFStreamSize = 64 * 1024 * 1024
+)
+
+// maxPDFOperators bounds the content stream operators read from a PDF,
+// against forms drawing other forms many times over.
+var maxPDFOperators = 10000000
+
+// PDF returns the text of the pages of a PDF document. The text is read
+// from the text operators of the page content streams, decoded through
+// the ToUnicode maps of the fonts. Scanned pages without text layer and
+// encrypted documents yield no text.
+func PDF(data []byte) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("%PDF-")) {
+		return "", errors.New("not a PDF document")
+	}
+
+	f := parsePDF(data)
+	if f.encrypted {
+		return "", errors.New("encrypted PDF documents are not supported")
+	}
+
+	var t textBuilder
+	for _, page := range f.pages() {
+		f.pageText(&t, page)
+		t.paragraph()
+	}
+	if f.ops > maxPDFOperators {
+		return "", errors.Errorf("PDF document has more than %d content operators", maxPDFOperators)
+	}
+
+	return t.String(), nil
+}
+
+type (
+	pdfName    string
+	pdfKeyword string
+	pdfString  []byte
+	pdfArray   []interface{}
+	pdfDict    map[string]interface{}
+	pdfRef     struct{ num, gen int }
+	pdfStream  struct {
+		dict pdfDict
+		raw  []byte
+	}
+)
+
+// pdfFile holds the objects of a PDF document, by object number.
+type pdfFile struct {
+	objects   map[int]interface{}
+	trailers  []pdfDict
+	encrypted bool
+	fonts     map[pdfRef]*pdfFont
+	// forms are the form XObjects being drawn
+	forms map[pdfRef]bool
+	// ops counts the content stream operators read
+	ops int
+}
+
+var (
+	pdfObjStart = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+	pdfTrailer  = regexp.MustCompile(`trailer\s*<<`)
+)
+
+// parsePDF reads all objects of data. Objects of incremental updates
+// replace the earlier ones.
+func parsePDF(data []byte) *pdfFile {
+	f := &pdfFile{objects: map[int]interface{}{}, fonts: map[pdfRef]*pdfFont{}, forms: map[pdfRef]bool{}}
+
+	var objStreams []*pdfStream
+	pos := 0
+	for _, m := range pdfObjStart.FindAllSubmatchIndex(data, -1) {
+		// matches within the data of a stream are not objects
+		if m[0] < pos {
+			continue
+		}
+		num, _ := strconv.Atoi(string(data[m[2]:m[3]]))
+		l := &pdfLexer{data: data, pos: m[1]}
+		v := l.value(0)
+		pos = l.pos
+
+		if dict, ok := v.(pdfDict); ok {
+			save := l.pos
+			if tok, _ := l.token(); tok == pdfKeyword("stream") {
+				s := &pdfStream{dict: dict, raw: l.streamData(dict)}
+				pos = l.pos
+				v = s
+				switch dict["Type"] {
+				case pdfName("ObjStm"):
+					objStreams = append(objStreams, s)
+				case pdfName("XRef"):
+					f.trailers = append(f.trailers, dict)
+				}
+			} else {
+				l.pos = save
+			}
+		}
+		f.objects[num] = v
+	}
+
+	for _, m := range pdfTrailer.FindAllIndex(data, -1) {
+		l := &pdfLexer{data: data, pos: m[1] - 2}
+		if dict, ok := l.value(0).(pdfDict); ok {
+			f.trailers = append(f.trailers, dict)
+		}
+	}
+	for _, t := range f.trailers {
+		if _, ok := t["Encrypt"]; ok {
+			f.encrypted = true
+		}
+	}
+
+	// objects compressed in object streams, unless defined outright
+	for _, s := range objStreams {
+		data, err := s.decode()
+		if err != nil {
+			continue
+		}
+		n, _ := s.dict["N"].(float64)
+		first, _ := s.dict["First"].(float64)
+		if first < 0 || first >= float64(len(data)) {
+			continue
+		}
+		// N comes from the file, the pairs of numbers the stream starts
+		// with are what it actually holds
+		l := &pdfLexer{data: data[:int(first)]}
+		offsets := map[int]int{}
+		var order []int
+		for i := 0; float64(i) < n; i++ {
+			num, ok := l.value(0).(float64)
+			off, offOK := l.value(0).(float64)
+			if !ok || !offOK {
+				break
+			}
+			if off < 0 || first+off >= float64(len(data)) {
+				continue
+			}
+			offsets[int(num)] = int(first) + int(off)
+			order = append(order, int(num))
+		}
+		for _, num := range order {
+			if _, ok := f.objects[num]; ok {
+				continue
+			}
+			l := &pdfLexer{data: data, pos: offsets[num]}
+			f.objects[num] = l.value(0)
+		}
+	}
+
+	return f
+}
+
+// resolve follows references to the object they refer to.
+func (f *pdfFile) resolve(v interface{}) interface{} {
+	for i := 0; i < maxPDFDepth; i++ {
+		ref, ok := v.(pdfRef)
+		if !ok {
+			return v
+		}
+		v = f.objects[ref.num]
+	}
+
+	return nil
+}
+
+func (f *pdfFile) dict(v interface{}) pdfDict {
+	switch v := f.resolve(v).(type) {
+	case pdfDict:
+		return v
+	case *pdfStream:
+		return v.dict
+	}
+
+	return nil
+}
+
+// pdfPage is a page along with the resources it inherits.
+type pdfPage struct {
+	dict      pdfDict
+	resources pdfDict
+}
+
+// pages returns the pages in document order, or in file order for
+// documents whose page tree cannot be found.
+func (f *pdfFile) pages() []pdfPage {
+	var root pdfDict
+	for _, t := range f.trailers {
+		if root = f.dict(t["Root"]); root != nil {
+			break
+		}
+	}
+	if root == nil {
+		for _, v := range f.objects {
+			if d, ok := v.(pdfDict); ok && d["Type"] == pdfName("Catalog") {
+				root = d
+			}
+		}
+	}
+
+	var pages []pdfPage
+	var walk func(node pdfDict, resources pdfDict, depth int)
+	walk = func(node pdfDict, resources pdfDict, depth int) {
+		if node == nil || depth > maxPDFDepth {
+			return
+		}
+		if r := f.dict(node["Resources"]); r != nil {
+			resources = r
+		}
+		if node["Type"] == pdfName("Page") {
+			pages = append(pages, pdfPage{dict: node, resources: resources})
+			return
+		}
+		kids, _ := f.resolve(node["Kids"]).(pdfArray)
+		for _, kid := range kids {
+			walk(f.dict(kid), resources, depth+1)
+		}
+	}
+	if root != nil {
+		walk(f.dict(root["Pages"]), nil, 0)
+	}
+	if len(pages) != 0 {
+		return pages
+	}
+
+	nums := make([]int, 0, len(f.objects))
+	for num := range f.objects {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	for _, num := range nums {
+		if d, ok := f.objects[num].(pdfDict); ok && d["Type"] == pdfName("Page") {
+			pages = append(pages, pdfPage{dict: d, resources: f.dict(d["Resources"])})
+		}
+	}
+
+	return pages
+}
+
+// pageText adds the text of a page to t.
+func (f *pdfFile) pageText(t *textBuilder, page pdfPage) {
+	var content []byte
+	switch c := f.resolve(page.dict["Contents"]).(type) {
+	case *pdfStream:
+		content, _ = c.decode()
+	case pdfArray:
+		for _, part := range c {
+			if s, ok := f.resolve(part).(*pdfStream); ok {
+				data, _ := s.decode()
+				content = append(append(content, data...), '\n')
+			}
+		}
+	}
+
+	f.contentText(t, content, page.resources, 0)
+}
+
+// contentText adds the text shown by the operators of a content stream.
+func (f *pdfFile) contentText(t *textBuilder, content []byte, resources pdfDict, depth int) {
+	if depth > maxPDFDepth {
+		return
+	}
+
+	var (
+		font     *pdfFont
+		operands []interface{}
+		lastY    float64
+	)
+	number := func(i int) float64 {
+		if i < 0 || i >= len(operands) {
+			return 0
+		}
+		n, _ := operands[i].(float64)
+		return n
+	}
+	show := func(v interface{}) {
+		if s, ok := v.(pdfString); ok {
+			t.write(font.decode(s))
+		}
+	}
+
+	l := &pdfLexer{data: content}
+	for {
+		tok, ok := l.token()
+		if !ok {
+			return
+		}
+		switch tok {
+		case pdfKeyword("["), pdfKeyword("<<"):
+			l.unread(tok)
+			operands = append(operands, l.value(0))
+			continue
+		}
+		op, isOp := tok.(pdfKeyword)
+		if !isOp {
+			operands = append(operands, tok)
+			continue
+		}
+		if f.ops++; f.ops > maxPDFOperators {
+			return
+		}
+
+		switch op {
+		case "BI":
+			// inline image data is binary
+			l.skipInlineImage()
+		case "Tf":
+			if len(operands) >= 2 {
+				name, _ := operands[len(operands)-2].(pdfName)
+				font = f.font(resources, name)
+			}
+		case "Tj":
+			if len(operands) != 0 {
+				show(operands[len(operands)-1])
+			}
+		case "'", `"`:
+			t.line()
+			if len(operands) != 0 {
+				show(operands[len(operands)-1])
+			}
+		case "TJ":
+			if len(operands) != 0 {
+				arr, _ := operands[len(operands)-1].(pdfArray)
+				for _, v := range arr {
+					// wide gaps between glyphs separate words
+					if n, ok := v.(float64); ok && n < -200 {
+						t.space = true
+					}
+					show(v)
+				}
+			}
+		case "Td", "TD":
+			if number(len(operands)-1) != 0 {
+				t.line()
+			} else {
+				t.space = true
+			}
+		case "T*":
+			t.line()
+		case "Tm":
+			if y := number(len(operands) - 1); y != lastY {
+				t.line()
+				lastY = y
+			} else {
+				t.space = true
+			}
+		case "Do":
+			if len(operands) != 0 {
+				name, _ := operands[len(operands)-1].(pdfName)
+				f.formText(t, resources, name, depth)
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+// formText adds the text of a form XObject drawn by a content stream.
+func (f *pdfFile) formText(t *textBuilder, resources pdfDict, name pdfName, depth int) {
+	xobjects := f.dict(resources["XObject"])
+	ref, isRef := xobjects[string(name)].(pdfRef)
+	// a form drawing itself, directly or through other forms, is drawn once
+	if isRef && f.forms[ref] {
+		return
+	}
+	form, ok := f.resolve(xobjects[string(name)]).(*pdfStream)
+	if !ok || form.dict["Subtype"] != pdfName("Form") {
+		return
+	}
+	if isRef {
+		f.forms[ref] = true
+		defer delete(f.forms, ref)
+	}
+	data, err := form.decode()
+	if err != nil {
+		return
+	}
+	if r := f.dict(form.dict["Resources"]); r != nil {
+		resources = r
+	}
+
+	f.contentText(t, data, resources, depth+1)
+}
+
+// pdfFont maps the character codes of a font to text.
+type pdfFont struct {
+	// codeLen is the number of bytes of a character code
+	codeLen int
+	// toUnicode maps codes to text, from the ToUnicode CMap of the font
+	toUnicode map[uint32]string
+}
+
+func (f *pdfFile) font(resources pdfDict, name pdfName) *pdfFont {
+	fonts := f.dict(resources["Font"])
+	ref, isRef := fonts[string(name)].(pdfRef)
+	if font, ok := f.fonts[ref]; isRef && ok {
+		return font
+	}
+
+	font := &pdfFont{codeLen: 1}
+	dict := f.dict(fonts[string(name)])
+	if dict == nil {
+		return font
+	}
+	if dict["Subtype"] == pdfName("Type0") {
+		font.codeLen = 2
+	}
+	if s, ok := f.resolve(dict["ToUnicode"]).(*pdfStream); ok {
+		if data, err := s.decode(); err == nil {
+			font.parseCMap(data)
+		}
+	}
+	if isRef {
+		f.fonts[ref] = font
+	}
+
+	return font
+}
+
+// parseCMap reads the mappings of a ToUnicode CMap.
+func (font *pdfFont) parseCMap(data []byte) {
+	font.toUnicode = map[uint32]string{}
+
+	var operands []interface{}
+	l := &pdfLexer{data: data}
+	for {
+		tok, ok := l.token()
+		if !ok {
+			return
+		}
+		if tok == pdfKeyword("[") {
+			l.unread(tok)
+			operands = append(operands, l.value(0))
+			continue
+		}
+		op, isOp := tok.(pdfKeyword)
+		if !isOp {
+			operands = append(operands, tok)
+			continue
+		}
+
+		switch op {
+		case "endcodespacerange":
+			if len(operands) >= 1 {
+				if lo, ok := operands[0].(pdfString); ok && len(lo) > 0 {
+					font.codeLen = len(lo)
+				}
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, _ := operands[i].(pdfString)
+				dst, _ := operands[i+1].(pdfString)
+				font.toUnicode[cmapCode(src)] = utf16BE(dst)
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, _ := operands[i].(pdfString)
+				hi, _ := operands[i+1].(pdfString)
+				from, to := cmapCode(lo), cmapCode(hi)
+				if to < from || to-from > 0xffff {
+					continue
+				}
+				switch dst := operands[i+2].(type) {
+				case pdfString:
+					base := []rune(utf16BE(dst))
+					if len(base) == 0 {
+						continue
+					}
+					for c := from; c <= to; c++ {
+						r := append([]rune{}, base...)
+						r[len(r)-1] += rune(c - from)
+						font.toUnicode[c] = string(r)
+					}
+				case pdfArray:
+					for j, v := range dst {
+						if s, ok := v.(pdfString); ok && from+uint32(j) <= to {
+							font.toUnicode[from+uint32(j)] = utf16BE(s)
+						}
+					}
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+// decode returns the text of a string shown with font.
+func (font *pdfFont) decode(s pdfString) string {
+	if font == nil || font.toUnicode == nil {
+		if font != nil && font.codeLen != 1 {
+			return ""
+		}
+		return decodeLatin1(s)
+	}
+
+	var b bytes.Buffer
+	for i := 0; i+font.codeLen <= len(s); i += font.codeLen {
+		code := cmapCode(s[i : i+font.codeLen])
+		if text, ok := font.toUnicode[code]; ok {
+			b.WriteString(text)
+		} else if font.codeLen == 1 {
+			b.WriteString(decodeLatin1(s[i : i+1]))
+		}
+	}
+
+	return b.String()
+}
+
+func cmapCode(b []byte) uint32 {
+	var code uint32
+	for _, c := range b {
+		code = code<<8 | uint32(c)
+	}
+
+	return code
+}
+
+func utf16BE(b []byte) string {
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+	}
+
+	return string(utf16.Decode(u))
+}
+
+// decode returns the data of s with its filters undone.
+func (s *pdfStream) decode() ([]byte, error) {
+	data := s.raw
+
+	var filters pdfArray
+	switch f := s.dict["Filter"].(type) {
+	case pdfName:
+		filters = pdfArray{f}
+	case pdfArray:
+		filters = f
+	}
+	params, _ := s.dict["DecodeParms"].(pdfDict)
+	if arr, ok := s.dict["DecodeParms"].(pdfArray); ok && len(arr) != 0 {
+		params, _ = arr[0].(pdfDict)
+	}
+
+	for _, filter := range filters {
+		var err error
+		switch filter {
+		case pdfName("FlateDecode"), pdfName("Fl"):
+			data, err = inflate(data)
+			if err == nil {
+				data, err = unpredict(data, params)
+			}
+		case pdfName("ASCIIHexDecode"), pdfName("AHx"):
+			data, err = hex.DecodeString(string(bytes.Map(func(r rune) rune {
+				if r == ' ' || r == '\n' || r == '\r' || r == '\t' || r == '>' {
+					return -1
+				}
+				return r
+			}, data)))
+		case pdfName("ASCII85Decode"), pdfName("A85"):
+			data = bytes.TrimSuffix(bytes.TrimSpace(data), []byte("~>"))
+			data = bytes.TrimPrefix(data, []byte("<~"))
+			data, err = readStream(ascii85.NewDecoder(bytes.NewReader(data)))
+		default:
+			return nil, errors.Errorf("unsupported stream filter %v", filter)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+// inflate decompresses zlib data, keeping what could be read of a
+// truncated stream.
+func inflate(data []byte) ([]byte, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	out, err := readStream(zr)
+	if err != nil && len(out) == 0 {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// readStream reads decoded stream data, failing when it exceeds
+// maxPDFStreamSize.
+func readStream(r io.Reader) ([]byte, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(r, maxPDFStreamSize+1))
+	if len(data) > maxPDFStreamSize {
+		return nil, errors.Errorf("stream is larger than %d MiB", maxPDFStreamSize/1024/1024)
+	}
+
+	return data, err
+}
+
+// unpredict undoes the PNG predictors of a Flate stream.
+func unpredict(data []byte, params pdfDict) ([]byte, error) {
+	predictor, _ := params["Predictor"].(float64)
+	if predictor < 10 {
+		return data, nil
+	}
+	columns, _ := params["Columns"].(float64)
+	if columns < 1 {
+		columns = 1
+	}
+	colors, _ := params["Colors"].(float64)
+	if colors < 1 {
+		colors = 1
+	}
+	bpc, _ := params["BitsPerComponent"].(float64)
+	if bpc < 1 {
+		bpc = 8
+	}
+	bpp := int(colors*bpc+7) / 8
+	rowLen := int(columns*colors*bpc+7) / 8
+
+	var out []byte
+	prev := make([]byte, rowLen)
+	for i := 0; i+rowLen+1 <= len(data); i += rowLen + 1 {
+		kind, row := data[i], append([]byte{}, data[i+1:i+1+rowLen]...)
+		for j := range row {
+			var left, upLeft byte
+			if j >= bpp {
+				left, upLeft = row[j-bpp], prev[j-bpp]
+			}
+			up := prev[j]
+			switch kind {
+			case 1:
+				row[j] += left
+			case 2:
+				row[j] += up
+			case 3:
+				row[j] += byte((int(left) + int(up)) / 2)
+			case 4:
+				row[j] += paeth(left, up, upLeft)
+			}
+		}
+		out = append(out, row...)
+		prev = row
+	}
+
+	return out, nil
+}
+
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	}
+
+	return c
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// pdfLexer reads the tokens and objects of PDF syntax.
+type pdfLexer struct {
+	data    []byte
+	pos     int
+	pending []interface{}
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isPDFDelim(c byte) bool {
+	return bytes.IndexByte([]byte("()<>[]{}/%"), c) >= 0
+}
+
+func (l *pdfLexer) unread(tok interface{}) {
+	l.pending = append(l.pending, tok)
+}
+
+// token returns the next number, name, string, keyword or delimiter.
+func (l *pdfLexer) token() (interface{}, bool) {
+	if n := len(l.pending); n != 0 {
+		tok := l.pending[n-1]
+		l.pending = l.pending[:n-1]
+		return tok, true
+	}
+
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if isPDFSpace(c) {
+			l.pos++
+			continue
+		}
+		if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		break
+	}
+	if l.pos >= len(l.data) {
+		return nil, false
+	}
+
+	c := l.data[l.pos]
+	switch {
+	case c == '(':
+		return l.literal(), true
+	case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+		l.pos += 2
+		return pdfKeyword("<<"), true
+	case c == '>' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '>':
+		l.pos += 2
+		return pdfKeyword(">>"), true
+	case c == '<':
+		return l.hexString(), true
+	case c == '/':
+		l.pos++
+		return pdfName(l.name()), true
+	case c == '[' || c == ']' || c == '{' || c == '}' || c == '>' || c == ')':
+		l.pos++
+		return pdfKeyword(l.data[l.pos-1 : l.pos]), true
+	}
+
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelim(l.data[l.pos]) {
+		l.pos++
+	}
+	word := string(l.data[start:l.pos])
+	if n, err := strconv.ParseFloat(word, 64); err == nil {
+		return n, true
+	}
+
+	return pdfKeyword(word), true
+}
+
+// value reads a complete object, references included.
+func (l *pdfLexer) value(depth int) interface{} {
+	tok, ok := l.token()
+	if !ok || depth > maxPDFDepth {
+		return nil
+	}
+
+	switch tok {
+	case pdfKeyword("<<"):
+		dict := pdfDict{}
+		for {
+			key, ok := l.token()
+			if !ok || key == pdfKeyword(">>") {
+				return dict
+			}
+			if name, isName := key.(pdfName); isName {
+				dict[string(name)] = l.value(depth + 1)
+			}
+		}
+	case pdfKeyword("["):
+		arr := pdfArray{}
+		for {
+			next, ok := l.token()
+			if !ok || next == pdfKeyword("]") {
+				return arr
+			}
+			l.unread(next)
+			arr = append(arr, l.value(depth+1))
+		}
+	case pdfKeyword("true"):
+		return true
+	case pdfKeyword("false"):
+		return false
+	case pdfKeyword("null"):
+		return nil
+	}
+
+	// "12 0 R" is a reference to object 12
+	if num, isNum := tok.(float64); isNum && len(l.pending) == 0 {
+		save := l.pos
+		gen, ok1 := l.token()
+		r, ok2 := l.token()
+		if g, isNum := gen.(float64); ok1 && ok2 && isNum && r == pdfKeyword("R") {
+			return pdfRef{num: int(num), gen: int(g)}
+		}
+		l.pending = l.pending[:0]
+		l.pos = save
+	}
+
+	return tok
+}
+
+func (l *pdfLexer) name() string {
+	var b bytes.Buffer
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelim(l.data[l.pos]) {
+		c := l.data[l.pos]
+		if c == '#' && l.pos+2 < len(l.data) {
+			if v, err := strconv.ParseUint(string(l.data[l.pos+1:l.pos+3]), 16, 8); err == nil {
+				b.WriteByte(byte(v))
+				l.pos += 3
+				continue
+			}
+		}
+		b.WriteByte(c)
+		l.pos++
+	}
+
+	return b.String()
+}
+
+func (l *pdfLexer) literal() pdfString {
+	var b bytes.Buffer
+	depth := 0
+	l.pos++
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return b.Bytes()
+			}
+			depth--
+		case '\\':
+			if l.pos >= len(l.data) {
+				return b.Bytes()
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r', '\n':
+				// a line continuation
+				if e == '\r' && l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(v)
+				} else {
+					c = e
+				}
+			}
+		}
+		b.WriteByte(c)
+	}
+
+	return b.Bytes()
+}
+
+func (l *pdfLexer) hexString() pdfString {
+	l.pos++
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		if c := l.data[l.pos]; !isPDFSpace(c) {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	l.pos++
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	s, _ := hex.DecodeString(string(digits))
+
+	return s
+}
+
+// streamData returns the raw data of the stream starting at the current
+// position, right after the stream keyword.
+func (l *pdfLexer) streamData(dict pdfDict) []byte {
+	if l.pos < len(l.data) && l.data[l.pos] == '\r' {
+		l.pos++
+	}
+	if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+		l.pos++
+	}
+	start := l.pos
+
+	if n, ok := dict["Length"].(float64); ok && n >= 0 && start+int(n) <= len(l.data) {
+		end := start + int(n)
+		rest := bytes.TrimLeft(l.data[end:], " \r\n\t")
+		if bytes.HasPrefix(rest, []byte("endstream")) {
+			l.pos = end
+			return l.data[start:end]
+		}
+	}
+
+	end := bytes.Index(l.data[start:], []byte("endstream"))
+	if end < 0 {
+		l.pos = len(l.data)
+		return l.data[start:]
+	}
+	l.pos = start + end + len("endstream")
+
+	return bytes.TrimRight(l.data[start:start+end], "\r\n")
+}
+
+// skipInlineImage moves past the data of an inline image.
+func (l *pdfLexer) skipInlineImage() {
+	i := bytes.Index(l.data[l.pos:], []byte("ID"))
+	if i < 0 {
+		l.pos = len(l.data)
+		return
+	}
+	l.pos += i + 2
+	for l.pos < len(l.data) {
+		i := bytes.Index(l.data[l.pos:], []byte("EI"))
+		if i < 0 {
+			l.pos = len(l.data)
+			return
+		}
+		l.pos += i + 2
+		if isPDFSpace(l.data[l.pos-3]) && (l.pos >= len(l.data) || isPDFSpace(l.data[l.pos])) {
+			return
+		}
+	}
+}
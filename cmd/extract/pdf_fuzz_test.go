@@ -0,0 +1,26 @@
+//go:build go1.18
+
+package extract
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// FuzzPDF needs the fuzzing support of Go 1.18, the module builds with Go 1.17.
+func FuzzPDF(f *testing.F) {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "sample.pdf"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(data)
+	for _, tt := range malformedPDFs {
+		f.Add(tt.data)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// malformed documents fail, they never panic
+		PDF(data)
+	})
+}
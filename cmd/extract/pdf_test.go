@@ -0,0 +1,140 @@
+package extract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// objStm returns a PDF document whose only object is an object stream
+// with the given /N and /First entries.
+func objStm(n, first, content string) []byte {
+	return []byte("%PDF-1.5\n1 0 obj\n<< /Type /ObjStm /N " + n + " /First " + first +
+		" /Length " + strconv.Itoa(len(content)) + " >>\nstream\n" + content + "\nendstream\nendobj\n%%EOF\n")
+}
+
+// pdfObjects returns a PDF document of the given objects, numbered from 1.
+func pdfObjects(objects ...string) []byte {
+	doc := "%PDF-1.4\n"
+	for i, obj := range objects {
+		doc += strconv.Itoa(i+1) + " 0 obj\n" + obj + "\nendobj\n"
+	}
+
+	return []byte(doc + "trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+}
+
+// pdfStreamObject returns a stream object of dict entries and content.
+func pdfStreamObject(dict, content string) string {
+	return "<< " + dict + " /Length " + strconv.Itoa(len(content)) + " >>\nstream\n" + content + "\nendstream"
+}
+
+// formsPDF returns a PDF document whose page draws form /F0, every form
+// /Fi drawing form /Fi+1 n times, the last one showing text.
+func formsPDF(forms, n int) []byte {
+	var xobjects string
+	for i := 0; i < forms; i++ {
+		xobjects += " /F" + strconv.Itoa(i) + " " + strconv.Itoa(i+5) + " 0 R"
+	}
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Resources << /XObject <<" + xobjects + " >> >> /Contents 4 0 R >>",
+		pdfStreamObject("", "/F0 Do"),
+	}
+	for i := 0; i < forms; i++ {
+		content := "BT (Drawn.) Tj ET"
+		if i+1 < forms {
+			content = strings.Repeat("/F"+strconv.Itoa(i+1)+" Do\n", n)
+		}
+		objects = append(objects, pdfStreamObject("/Type /XObject /Subtype /Form /BBox [0 0 10 10]", content))
+	}
+
+	return pdfObjects(objects...)
+}
+
+// selfDrawingPDF is a PDF document whose page draws form /F, which
+// draws itself twice.
+var selfDrawingPDF = pdfObjects(
+	"<< /Type /Catalog /Pages 2 0 R >>",
+	"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+	"<< /Type /Page /Parent 2 0 R /Resources << /XObject << /F 5 0 R >> >> /Contents 4 0 R >>",
+	pdfStreamObject("", "/F Do"),
+	pdfStreamObject("/Type /XObject /Subtype /Form /BBox [0 0 10 10]", "/F Do /F Do"),
+)
+
+func TestPDFForms(t *testing.T) {
+	text, err := PDF(formsPDF(3, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Drawn.Drawn.Drawn.Drawn."; strings.ReplaceAll(text, " ", "") != want {
+		t.Errorf("PDF() = %q, want %q", text, want)
+	}
+
+	// 100^4 draws of the last form
+	defer func(max int) { maxPDFOperators = max }(maxPDFOperators)
+	maxPDFOperators = 100000
+	if _, err := PDF(formsPDF(5, 100)); err == nil || !strings.Contains(err.Error(), "content operators") {
+		t.Errorf("PDF() of forms drawing forms many times over error = %v, want too many operators", err)
+	}
+}
+
+func TestPDFStreamSize(t *testing.T) {
+	var b bytes.Buffer
+	zw := zlib.NewWriter(&b)
+	zw.Write(make([]byte, maxPDFStreamSize+1))
+	zw.Close()
+
+	tests := []struct {
+		name string
+		s    *pdfStream
+	}{
+		{"flate", &pdfStream{dict: pdfDict{"Filter": pdfName("FlateDecode")}, raw: b.Bytes()}},
+		// every z stands for four zero bytes
+		{"ascii85", &pdfStream{dict: pdfDict{"Filter": pdfName("ASCII85Decode")}, raw: bytes.Repeat([]byte("z"), maxPDFStreamSize/4+1)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if data, err := tt.s.decode(); err == nil || !strings.Contains(err.Error(), "larger than") {
+				t.Errorf("decode() of %d bytes error = %v, want too large", len(data), err)
+			}
+		})
+	}
+
+	b.Reset()
+	zw = zlib.NewWriter(&b)
+	zw.Write([]byte("BT (Small.) Tj ET"))
+	zw.Close()
+	s := &pdfStream{dict: pdfDict{"Filter": pdfName("FlateDecode")}, raw: b.Bytes()}
+	if data, err := s.decode(); err != nil || string(data) != "BT (Small.) Tj ET" {
+		t.Errorf("decode() = %q, %v", data, err)
+	}
+}
+
+// malformedPDFs are broken PDF documents without text.
+var malformedPDFs = []struct {
+	name string
+	data []byte
+}{
+	{"negative first", objStm("2", "-50", "1 0 2 5 << >> << >>")},
+	{"first past the end", objStm("2", "4000", "1 0 2 5 << >> << >>")},
+	{"huge count", objStm("2000000000", "4", "1 0 2 5 << >> << >>")},
+	{"negative offset", objStm("1", "4", "1 -9 (Hidden text)")},
+	{"offset past the end", objStm("1", "4", "1 900 (Hidden text)")},
+	{"truncated", []byte("%PDF-1.4\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R")},
+	{"no objects", []byte("%PDF-1.4\n%%EOF\n")},
+	{"form drawing itself", selfDrawingPDF},
+}
+
+func TestPDFMalformed(t *testing.T) {
+	for _, tt := range malformedPDFs {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Text("broken.pdf", tt.data)
+			if err == nil || !strings.Contains(err.Error(), "no text found") {
+				t.Errorf("Text() error = %v, want no text found", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	identitiesFilename = "identities.yaml"
+	defaultIdentity    = "default"
+
+	// identityEnv selects an identity when '--identity' is not given
+	identityEnv = "RCLI_IDENTITY"
+)
+
+// identityName is what identity names are made of, so they can be typed
+// and completed in a shell without quoting.
+var identityNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// identitiesFile maps human-friendly names to the user UUIDs documents
+// are indexed under, each UUID is a separate index on the server, e.g.:
+//
+//	identities:
+//	  default: 0b6c4d4e-95d8-4bd4-a3f4-1a43e1e4d7b1
+//	  team: 5d8e2f0a-8b1c-4b0e-9f5e-3c1e7a6d2b90
+//	first_indexed:
+//	  0b6c4d4e-95d8-4bd4-a3f4-1a43e1e4d7b1: 2020-12-14T09:30:00Z
+type identitiesFile struct {
+	// Current is where the identity in use was kept before the config
+	// file, it is moved there by migrateSettings
+	Current    string            `yaml:"current,omitempty"`
+	Identities map[string]string `yaml:"identities"`
+	// FirstIndexed is when the index of a UUID got its first document
+	// through this rcli, the start of its lifetime on expiring servers
+	FirstIndexed map[string]time.Time `yaml:"first_indexed,omitempty"`
+}
+
+func identitiesPath() string {
+	return filepath.Join(getConfigDir(), identitiesFilename)
+}
+
+// loadIdentities reads the identities file. The first time, it is created
+// with a "default" identity, which is the UUID rcli used before identities
+// were introduced if there is one.
+func loadIdentities() (identitiesFile, error) {
+	ids := identitiesFile{}
+
+	data, err := ioutil.ReadFile(identitiesPath())
+	if err != nil && !os.IsNotExist(err) {
+		return ids, err
+	}
+	if err == nil {
+		if err := yaml.Unmarshal(data, &ids); err != nil {
+			return ids, errors.WithMessagef(err, "bad identities file %s", identitiesPath())
+		}
+	}
+	if ids.Identities == nil {
+		ids.Identities = map[string]string{}
+	}
+	if len(ids.Identities) != 0 {
+		return ids, nil
+	}
+
+	id := strings.TrimSpace(loadUserUUID())
+	if id == "" {
+		id = uuid.New().String()
+	}
+	ids.Identities[defaultIdentity] = id
+	if err := storeIdentities(ids); err != nil {
+		return ids, errors.WithMessage(err, "failed to store identities")
+	}
+
+	return ids, nil
+}
+
+func storeIdentities(ids identitiesFile) error {
+	data, err := yaml.Marshal(ids)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(identitiesPath(), data)
+}
+
+// resolveIdentity returns the name and UUID of the identity to use.
+// Name selection order is the '--identity' flag, RCLI_IDENTITY, the
+// identity set with 'rcli identity use', then "default".
+func resolveIdentity(name string, s settings) (string, string, error) {
+	ids, err := loadIdentities()
+	if err != nil {
+		return "", "", err
+	}
+
+	if name == "" {
+		name = os.Getenv(identityEnv)
+	}
+	if name == "" {
+		name = s.Identity
+	}
+	if name == "" {
+		name = defaultIdentity
+	}
+
+	id, ok := ids.Identities[name]
+	if !ok {
+		return "", "", errors.Errorf("unknown identity %q, known identities: %v", name, ids.names())
+	}
+
+	return name, id, nil
+}
+
+// names lists identity names alphabetically.
+func (ids identitiesFile) names() []string {
+	names := make([]string, 0, len(ids.Identities))
+	for name := range ids.Identities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// validIdentityName checks name can be used for a new identity.
+func validIdentityName(name string) error {
+	if !identityNamePattern.MatchString(name) {
+		return errors.Errorf("bad identity name %q, use letters, digits, '.', '_' and '-'", name)
+	}
+
+	return nil
+}
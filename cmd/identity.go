@@ -0,0 +1,267 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const useFlag = "use"
+
+// newIdentityCmd represents the identity management commands, active is
+// the name of the identity selected for this run.
+func newIdentityCmd(active *string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "identity",
+		Short: "Manage identities, each with its own search index",
+		Long: `Documents are indexed under the identity in use, every identity has a separate index.
+
+Identities are stored in ` + identitiesFilename + ` in the configuration directory.
+Select one for a single command with '--identity' or RCLI_IDENTITY, or
+for all commands with 'rcli identity use'. To search the same index as a
+teammate, import the UUID they get from 'rcli identity show'.`,
+	}
+
+	cmd.AddCommand(
+		newIdentityListCmd(active),
+		newIdentityNewCmd(),
+		newIdentityUseCmd(),
+		newIdentityRmCmd(active),
+		newIdentityShowCmd(active),
+		newIdentityImportCmd(),
+	)
+
+	return cmd
+}
+
+func newIdentityListCmd(active *string) *cobra.Command {
+	return &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List identities, the one in use is marked with '*'",
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			ids, err := loadIdentities()
+			if err != nil {
+				printErr(err.Error())
+				return
+			}
+
+			for _, name := range ids.names() {
+				mark := " "
+				if name == *active {
+					mark = "*"
+				}
+				fmt.Printf("%s %-20s %s\n", mark, name, ids.Identities[name])
+			}
+		},
+	}
+}
+
+func newIdentityNewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "new <name>",
+		Short: "Create an identity with an empty index",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			use, _ := cmd.Flags().GetBool(useFlag)
+			addIdentity(args[0], uuid.New().String(), use)
+		},
+		Example: "identity new project-x\r\nidentity new project-x --use",
+	}
+
+	cmd.Flags().Bool(useFlag, false, "Use the new identity from now on")
+
+	return cmd
+}
+
+func newIdentityImportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import <name> <uuid>",
+		Short: "Add an identity shared by someone else",
+		Long: `Add an identity shared by someone else, so both of you work on the same index.
+
+The UUID is printed by 'rcli identity show' of the one sharing it.`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			id, err := uuid.Parse(args[1])
+			if err != nil {
+				printErr(fmt.Sprintf("bad identity UUID %q: %v", args[1], err))
+				return
+			}
+
+			use, _ := cmd.Flags().GetBool(useFlag)
+			addIdentity(args[0], id.String(), use)
+		},
+		Example: "identity import team 5d8e2f0a-8b1c-4b0e-9f5e-3c1e7a6d2b90 --use",
+	}
+
+	cmd.Flags().Bool(useFlag, false, "Use the imported identity from now on")
+
+	return cmd
+}
+
+func newIdentityUseCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               "use <name>",
+		Short:             "Use an identity from now on",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeIdentityName,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := useIdentity(args[0]); err != nil {
+				printErr(err.Error())
+				return
+			}
+
+			printMsg(fmt.Sprintf("Using identity %q.", args[0]))
+		},
+	}
+}
+
+func newIdentityRmCmd(active *string) *cobra.Command {
+	return &cobra.Command{
+		Use:     "rm <name>",
+		Aliases: []string{"delete"},
+		Short:   "Forget an identity",
+		Long: `Forget an identity. Its documents stay on the server until the index expires,
+they can be searched again by importing its UUID.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeIdentityName,
+		Run: func(cmd *cobra.Command, args []string) {
+			name := args[0]
+			ids, err := loadIdentities()
+			if err != nil {
+				printErr(err.Error())
+				return
+			}
+			id, ok := ids.Identities[name]
+			if !ok {
+				printErr(fmt.Sprintf("unknown identity %q, known identities: %v", name, ids.names()))
+				return
+			}
+			s, err := loadSettings()
+			if err != nil {
+				printErr(err.Error())
+				return
+			}
+			if name == s.Identity || (s.Identity == "" && name == defaultIdentity) || name == *active {
+				printErr(fmt.Sprintf("identity %q is in use, switch to another one with `rcli identity use` first", name))
+				return
+			}
+
+			delete(ids.Identities, name)
+			if err := storeIdentities(ids); err != nil {
+				printErr(fmt.Sprintf("failed to store identities: %v", err))
+				return
+			}
+
+			printMsg(fmt.Sprintf("Removed identity %q, use `rcli identity import %s %s` to restore it.", name, name, id))
+		},
+	}
+}
+
+func newIdentityShowCmd(active *string) *cobra.Command {
+	return &cobra.Command{
+		Use:               "show [name]",
+		Short:             "Show the UUID of an identity, the one in use by default",
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeIdentityName,
+		Run: func(cmd *cobra.Command, args []string) {
+			name := *active
+			if len(args) != 0 {
+				name = args[0]
+			}
+
+			ids, err := loadIdentities()
+			if err != nil {
+				printErr(err.Error())
+				return
+			}
+			id, ok := ids.Identities[name]
+			if !ok {
+				printErr(fmt.Sprintf("unknown identity %q, known identities: %v", name, ids.names()))
+				return
+			}
+
+			fmt.Printf("Name: %s\nUUID: %s\n", name, id)
+			printMsg(fmt.Sprintf("Others can search this index after `rcli identity import %s %s`.", name, id))
+		},
+	}
+}
+
+// addIdentity stores a new identity, optionally making it the current one.
+func addIdentity(name, id string, use bool) {
+	if err := validIdentityName(name); err != nil {
+		printErr(err.Error())
+		return
+	}
+
+	ids, err := loadIdentities()
+	if err != nil {
+		printErr(err.Error())
+		return
+	}
+	if _, ok := ids.Identities[name]; ok {
+		printErr(fmt.Sprintf("identity %q already exists", name))
+		return
+	}
+
+	ids.Identities[name] = id
+	if err := storeIdentities(ids); err != nil {
+		printErr(fmt.Sprintf("failed to store identities: %v", err))
+		return
+	}
+	if use {
+		if err := useIdentity(name); err != nil {
+			printErr(err.Error())
+			return
+		}
+	}
+
+	msg := fmt.Sprintf("Added identity %q.", name)
+	if use {
+		msg = fmt.Sprintf("Added identity %q and using it from now on.", name)
+	}
+	printMsg(msg)
+}
+
+// useIdentity makes name the identity in use from now on.
+func useIdentity(name string) error {
+	s, err := loadSettings()
+	if err != nil {
+		return err
+	}
+	if err := settingKeys["identity"].set(&s, name); err != nil {
+		return err
+	}
+
+	return errors.WithMessage(storeSettings(s), "failed to store config")
+}
+
+// completeIdentityName completes the first argument with identity names.
+func completeIdentityName(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) != 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	return completeIdentityFlag(cmd, args, toComplete)
+}
+
+// completeIdentityFlag completes a flag value with identity names.
+func completeIdentityFlag(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	ids, err := loadIdentities()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	var names []string
+	for _, name := range ids.names() {
+		if strings.HasPrefix(name, toComplete) {
+			names = append(names, name)
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
@@ -1,20 +1,42 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"math/rand"
+	"os"
 	"sort"
 	"strings"
+	"time"
 
-	api "github.com/repustate/rcli/api-client/v4"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+
+	api "github.com/repustate/rcli/api-client/v4"
+	"github.com/repustate/rcli/cmd/chunk"
+	"github.com/repustate/rcli/cmd/langid"
 )
 
 const (
 	textFlag = "text"
 	fileFlag = "file"
 	langFlag = "lang"
+
+	recursiveFlag   = "recursive"
+	concurrencyFlag = "concurrency"
+	resumeFlag      = "resume"
+
+	chunkSizeFlag    = "chunk-size"
+	chunkOverlapFlag = "chunk-overlap"
+	cueWindowFlag    = "cue-window"
+
+	// minLangConfidence is the confidence a detected language needs to be
+	// used, documents are indexed in the default language otherwise
+	minLangConfidence = 0.7
+	// unsureLangConfidence is the confidence below which a detected
+	// language is reported as a guess
+	unsureLangConfidence = 0.8
 )
 
 var (
@@ -31,65 +53,438 @@ var (
 	}
 )
 
+// checkLang verifies lang is a language code supported by the demo.
+func checkLang(lang string) error {
+	for _, l := range validLangs {
+		if l == lang {
+			return nil
+		}
+	}
+	return errors.Errorf("unsupported language %q, valid languages: %s", lang, strings.Join(validLangs, ", "))
+}
+
+// detectLang identifies the language of text, leaving it to the server
+// when the detection is too unsure.
+func detectLang(text string) langid.Result {
+	res := langid.Detect(text)
+	if res.Confidence < minLangConfidence {
+		return langid.Result{}
+	}
+
+	return res
+}
+
 // registerCmd represents the text index command
-func newIndexCmd(c *api.Client) *cobra.Command {
+func newIndexCmd(c api.Indexer, user identity, p *profile) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "index",
+		Use:   "index [files, directories or glob patterns...]",
 		Short: "Add document to a semantic search index",
 		Long: fmt.Sprintf(`Add a document to a semantic search index.
 Usage example:
 rcli index -t="The weather in London is good" -l=en
 
+Several files are indexed at once when more than one '--file' is given,
+a glob pattern matches multiple files or '--recursive' is used with a directory.
+Such bulk runs are recorded as jobs: an interrupted job can be continued with
+'--resume <job>', skipping documents which already reached the server.
+
+The text of HTML, Markdown, PDF, DOCX, ODT and EPUB files is extracted
+before indexing, other binary files are skipped as unsupported.
+
+Text piped to rcli, or given with '--file -', is indexed as a single document.
+With '--split newline' or '--split nul' every line or NUL-terminated record of
+the standard input is a separate document, indexed as it is read:
+rcli index --split newline < reviews.txt
+
+With '--format csv' or '--format jsonl' every row of the given files is a
+separate document, its text, language, id and metadata read from the named
+columns or fields:
+rcli index --format csv --text-field body --id-field id --meta-field author reviews.csv
+
+Without '--lang', or a language set in the connection profile or config file,
+the language of every document is detected offline.
+
+With '--mbox' or '--maildir' every message of mbox files or Maildir folders is
+a separate document, indexed with its subject, sender and date as metadata.
+Quoted replies, signatures and attachments are left out:
+rcli index --mbox support.mbox --maildir ~/Maildir/.Support
+
+Documents longer than '--chunk-size' characters are split into passages at
+paragraph and sentence boundaries, consecutive passages sharing up to
+'--chunk-overlap' characters. Every passage is indexed as a document with the
+'parent', 'chunk', 'chunks' and 'source' metadata, and search results group
+the matching passages of a document. Text given with '--text' is indexed whole.
+
+The cues of SubRip (.srt) and WebVTT (.vtt) subtitles and transcripts are
+grouped into windows of '--cue-window', each window being a passage with the
+'start' and 'end' timecodes of its cues as metadata. Search results show where
+a window starts in the recording, e.g. 'talk.vtt @ 00:12:31'.
+
+
 Valid language codes: %s`, strings.Join(validLangs, ", ")),
 		Run: func(cmd *cobra.Command, args []string) {
 			text := cmd.Flag(textFlag).Value.String()
-			filename := cmd.Flag(fileFlag).Value.String()
 			lang := cmd.Flag(langFlag).Value.String()
+			patterns, _ := cmd.Flags().GetStringSlice(fileFlag)
+			patterns = append(patterns, args...)
+			recursive, _ := cmd.Flags().GetBool(recursiveFlag)
+			concurrency, _ := cmd.Flags().GetInt(concurrencyFlag)
+			resume := cmd.Flag(resumeFlag).Value.String()
+			split := cmd.Flag(splitFlag).Value.String()
+			format := cmd.Flag(formatFlag).Value.String()
+			fields := fieldMap{
+				Text:      cmd.Flag(textFieldFlag).Value.String(),
+				Lang:      cmd.Flag(langFieldFlag).Value.String(),
+				ID:        cmd.Flag(idFieldFlag).Value.String(),
+				Delimiter: cmd.Flag(delimiterFlag).Value.String(),
+			}
+			fields.Meta, _ = cmd.Flags().GetStringSlice(metaFieldFlag)
+			mboxes, _ := cmd.Flags().GetStringSlice(mboxFlag)
+			maildirs, _ := cmd.Flags().GetStringSlice(maildirFlag)
+			var chunking chunk.Options
+			chunking.Size, _ = cmd.Flags().GetInt(chunkSizeFlag)
+			chunking.Overlap, _ = cmd.Flags().GetInt(chunkOverlapFlag)
+			window, _ := cmd.Flags().GetDuration(cueWindowFlag)
+
+			var j *job
+			if resume != "" {
+				var err error
+				if j, err = loadJob(resume); err != nil {
+					printErr(fmt.Sprintf("failed to resume job: %v", err))
+					return
+				}
+				defer j.close()
+				if j.Mirror != "" {
+					printErr(fmt.Sprintf("job %s replays a mirror, resume it with `rcli reindex --resume %s`", j.ID, j.ID))
+					return
+				}
+
+				// resumed jobs default to the inputs of the original run
+				if text == "" && len(patterns) == 0 && len(mboxes) == 0 && len(maildirs) == 0 {
+					patterns, recursive = j.Patterns, j.Recursive
+					mboxes, maildirs = j.Mbox, j.Maildir
+				}
+				if split == "" {
+					split = j.Split
+				}
+				if format == "" && j.Format != "" {
+					format, fields = j.Format, *j.Fields
+				}
+				if lang == "" {
+					lang = j.Lang
+				}
+				if !cmd.Flags().Changed(chunkSizeFlag) && !cmd.Flags().Changed(chunkOverlapFlag) {
+					chunking = j.chunking()
+				}
+				j.Chunk = &chunking
+				if !cmd.Flags().Changed(cueWindowFlag) {
+					window = j.CueWindow
+				}
+				j.CueWindow = window
+			}
+
+			if chunking.Size < 0 || chunking.Overlap < 0 || (chunking.Size > 0 && chunking.Overlap >= chunking.Size) {
+				printErr(fmt.Sprintf("'--%s' and '--%s' must not be negative, the overlap must be smaller than the size", chunkSizeFlag, chunkOverlapFlag))
+				return
+			}
+			if window < 0 {
+				printErr(fmt.Sprintf("'--%s' must not be negative", cueWindowFlag))
+				return
+			}
+
+			if lang != "" {
+				if err := checkLang(lang); err != nil {
+					printErr(err.Error())
+					return
+				}
+			} else {
+				lang = p.Lang
+			}
+			bulk := bulkOptions{lang: lang, user: user(), concurrency: concurrency, chunking: chunking, window: window}
+
+			if len(mboxes) != 0 || len(maildirs) != 0 {
+				if text != "" || len(patterns) != 0 || split != "" || format != "" {
+					printErr(fmt.Sprintf("'--%s' and '--%s' cannot be combined with '--%s', '--%s', '--%s' or '--%s'", mboxFlag, maildirFlag, textFlag, fileFlag, splitFlag, formatFlag))
+					return
+				}
+				stdin := false
+				for _, mbox := range mboxes {
+					stdin = stdin || mbox == stdinPath
+				}
+				switch {
+				case stdin && len(mboxes)+len(maildirs) > 1:
+					printErr(fmt.Sprintf("'--%s %s' cannot be combined with other mailboxes", mboxFlag, stdinPath))
+					return
+				case stdin && j != nil && !stdinRedirected():
+					printErr(fmt.Sprintf("job %s read the standard input, pipe the same input again to resume it", j.ID))
+					return
+				}
+
+				bulk.manifest = job{Mbox: mboxes, Maildir: maildirs}
+				runBulk(cmd.Context(), c, j, "the messages of "+describeMailboxes(mboxes, maildirs), streamMail(cmd.Context(), mboxes, maildirs), 0, bulk)
+				return
+			}
 
-			if text == "" && filename == "" {
-				msg := fmt.Sprintf("one of '--text' or '--filename' is required")
+			if text == "" && len(patterns) == 0 && stdinRedirected() {
+				patterns = []string{stdinPath}
+			}
+
+			if text == "" && len(patterns) == 0 {
+				msg := fmt.Sprintf("one of '--text' or '--file' is required")
 				printErr(msg)
 				cmd.Usage()
 				return
 			}
-			if text != "" && filename != "" {
-				msg := fmt.Sprintf("only one of '--text' or '--filename' should be used")
+			if text != "" && len(patterns) != 0 {
+				msg := fmt.Sprintf("only one of '--text' or '--file' should be used")
 				printErr(msg)
 				cmd.Usage()
 				return
 			}
 
-			if text == "" {
-				data, err := ioutil.ReadFile(filename)
+			if text != "" && split != "" {
+				printErr(fmt.Sprintf("'--%s' only applies to the standard input", splitFlag))
+				return
+			}
+
+			if format == "" {
+				for _, flag := range []string{textFieldFlag, langFieldFlag, idFieldFlag, metaFieldFlag, delimiterFlag} {
+					if cmd.Flags().Changed(flag) {
+						printErr(fmt.Sprintf("'--%s' requires '--%s'", flag, formatFlag))
+						return
+					}
+				}
+			} else {
+				if err := fields.check(format); err != nil {
+					printErr(err.Error())
+					return
+				}
+				if text != "" || split != "" {
+					printErr(fmt.Sprintf("'--%s' cannot be combined with '--%s' or '--%s'", formatFlag, textFlag, splitFlag))
+					return
+				}
+			}
+
+			if text != "" {
+				detected := langid.Result{}
+				if lang == "" {
+					detected = detectLang(text)
+					lang = detected.Lang
+				}
+				res, err := c.IndexContext(cmd.Context(), text, lang, user())
+				printIndexResult(res, err, detected)
+				return
+			}
+
+			stdin := false
+			for _, pattern := range patterns {
+				stdin = stdin || pattern == stdinPath
+			}
+			switch {
+			case stdin && len(patterns) > 1:
+				printErr(fmt.Sprintf("'--%s %s' cannot be combined with other files", fileFlag, stdinPath))
+				return
+			case split != "" && !stdin:
+				printErr(fmt.Sprintf("'--%s' only applies to the standard input", splitFlag))
+				return
+			case split != "" && split != splitNewline && split != splitNUL:
+				printErr(fmt.Sprintf("bad '--%s' mode %q, use one of: %s", splitFlag, split, strings.Join(splitModes, ", ")))
+				return
+			case stdin && j != nil && !stdinRedirected():
+				printErr(fmt.Sprintf("job %s read the standard input, pipe the same input again to resume it", j.ID))
+				return
+			}
+
+			if format != "" {
+				inputs := patterns
+				if !stdin {
+					var err error
+					if inputs, err = collectFiles(patterns, recursive); err != nil {
+						printErr(err.Error())
+						return
+					}
+				}
+
+				bulk.manifest = job{Patterns: patterns, Recursive: recursive, Format: format, Fields: &fields}
+				what := fmt.Sprintf("the %s records of %s", strings.ToUpper(format), describeInputs(inputs))
+				runBulk(cmd.Context(), c, j, what, streamStructured(cmd.Context(), inputs, format, fields), 0, bulk)
+				return
+			}
+
+			if stdin && split == "" {
+				data, err := ioutil.ReadAll(os.Stdin)
+				if err != nil {
+					printErr(fmt.Sprintf("failed to read standard input: %v", err))
+					return
+				}
+				if strings.TrimSpace(string(data)) == "" {
+					printErr("no text to index on the standard input")
+					return
+				}
+
+				// long documents are indexed as a job of passages
+				if passages := chunk.Split(string(data), chunking); len(passages) > 1 {
+					bulk.manifest = job{Patterns: patterns}
+					what := fmt.Sprintf("the standard input in %d passages", len(passages))
+					runBulk(cmd.Context(), c, j, what, sendDocs(cmd.Context(), []bulkDoc{{Path: "stdin", Text: string(data)}}), 1, bulk)
+					return
+				}
+
+				detected := langid.Result{}
+				if lang == "" {
+					detected = detectLang(string(data))
+					lang = detected.Lang
+				}
+				res, err := c.IndexContext(withSource(cmd.Context(), "stdin"), string(data), lang, user())
+				printIndexResult(res, err, detected)
+				return
+			}
+
+			if stdin {
+				bulk.manifest = job{Patterns: patterns, Split: split}
+				runBulk(cmd.Context(), c, j, "records of the standard input", streamRecords(cmd.Context(), os.Stdin, split), 0, bulk)
+				return
+			}
+
+			files, err := collectFiles(patterns, recursive)
+			if err != nil {
+				printErr(err.Error())
+				return
+			}
+
+			passages := 0
+			if len(files) == 1 && !recursive && j == nil {
+				data, err := ioutil.ReadFile(files[0])
 				if err != nil {
 					msg := fmt.Sprintf("failed to read file: %v", err)
 					printErr(msg)
 					return
 				}
+				text, err := extractText(files[0], data)
+				if err != nil {
+					printErr(fmt.Sprintf("%s: %v", files[0], err))
+					return
+				}
 
-				text = string(data)
+				// long documents and subtitles are indexed as a job of passages
+				parts := splitDoc(files[0], data, text, chunking, window)
+				passages = len(parts)
+				if passages == 1 && parts[0].meta == nil {
+					detected := langid.Result{}
+					if lang == "" {
+						detected = detectLang(text)
+						lang = detected.Lang
+					}
+					res, err := c.IndexContext(withSource(cmd.Context(), files[0]), text, lang, user())
+					printIndexResult(res, err, detected)
+					return
+				}
 			}
 
-			res, err := c.Index(text, lang, userUuid)
-			printIndexResult(res, err)
+			what := fmt.Sprintf("%d documents", len(files))
+			if passages != 0 {
+				what = fmt.Sprintf("%s in %d passages", files[0], passages)
+			}
+			docs := make([]bulkDoc, len(files))
+			for i, f := range files {
+				docs[i] = bulkDoc{Path: f}
+			}
+			bulk.manifest = job{Patterns: patterns, Recursive: recursive}
+			runBulk(cmd.Context(), c, j, what, sendDocs(cmd.Context(), docs), len(docs), bulk)
 		},
-		Example: "index --text=\"Paris is the capitol of France.\" -l=en\r\nindex --filename=~/myfiles/data.txt",
+		Example: "index --text=\"Paris is the capitol of France.\" -l=en\r\nindex --file=~/myfiles/data.txt\r\n" +
+			"index -f=a.txt -f=b.txt\r\nindex \"notes/*.txt\"\r\nindex --recursive --concurrency=8 ./corpus\r\n" +
+			"pdftotext report.pdf - | rcli index\r\ncat reviews.txt | rcli index --split newline\r\n" +
+			"index --format jsonl --text-field review.body --lang-field lang export.jsonl\r\n" +
+			"index --chunk-size 1000 --chunk-overlap 100 handbook.pdf\r\nindex --mbox export.mbox",
 	}
 
 	cmd.Flags().StringP(textFlag, "t", "", "Text to index")
-	cmd.Flags().StringP(fileFlag, "f", "", "File with text content to index")
+	cmd.Flags().StringSliceP(fileFlag, "f", nil, "Files or glob patterns to index, may be repeated")
 	cmd.MarkFlagFilename(fileFlag)
-	cmd.Flags().StringP(langFlag, "l", "", "Content language (default is English)")
+	cmd.Flags().BoolP(recursiveFlag, "r", false, "Index all files in the given directories and their subdirectories")
+	cmd.Flags().Int(concurrencyFlag, 4, "Number of documents indexed in parallel")
+	cmd.Flags().String(resumeFlag, "", "Resume an interrupted bulk index job, skipping documents it already indexed")
+	cmd.Flags().String(splitFlag, "", fmt.Sprintf("Index every record of the standard input as a document, records end with a: %s", strings.Join(splitModes, ", ")))
+	cmd.RegisterFlagCompletionFunc(splitFlag, func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return splitModes, cobra.ShellCompDirectiveNoFileComp
+	})
+	cmd.Flags().StringP(langFlag, "l", "", "Content language (default is detected)")
+	cmd.Flags().String(formatFlag, "", fmt.Sprintf("Index every record of structured files as a document, files are in: %s", strings.Join(formats, ", ")))
+	cmd.RegisterFlagCompletionFunc(formatFlag, func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return formats, cobra.ShellCompDirectiveNoFileComp
+	})
+	cmd.Flags().String(textFieldFlag, defaultTextField, "Field or column holding the text of a '--format' record")
+	cmd.Flags().String(langFieldFlag, "", "Field or column holding the language of a '--format' record (default is '--lang')")
+	cmd.Flags().String(idFieldFlag, "", "Field or column holding the document id of a '--format' record (default is chosen by the server)")
+	cmd.Flags().StringSlice(metaFieldFlag, nil, "Fields or columns of a '--format' record carried along as document metadata, may be repeated")
+	cmd.Flags().String(delimiterFlag, "", "Character separating CSV columns, e.g. ';' or 'tab' (default ',')")
+	cmd.Flags().StringSlice(mboxFlag, nil, "Index every message of an mbox file as a document, may be repeated")
+	cmd.MarkFlagFilename(mboxFlag)
+	cmd.Flags().StringSlice(maildirFlag, nil, "Index every message of a Maildir folder and its subfolders as a document, may be repeated")
+	cmd.MarkFlagDirname(maildirFlag)
+	cmd.Flags().Int(chunkSizeFlag, 2000, "Length in characters of the passages long documents are split into, 0 to index documents whole")
+	cmd.Flags().Int(chunkOverlapFlag, 200, "Characters at most shared by consecutive passages of a split document")
+	cmd.Flags().Duration(cueWindowFlag, 30*time.Second, "Length of the windows of subtitle cues indexed as a passage, 0 to index every cue")
 
 	return cmd
 }
 
-func printIndexResult(res *api.IndexResult, err error) {
+// printIndexResult prints the analysis of an indexed document, detected is
+// its language unless it was given.
+// bulkOptions are the settings of the bulk runs of the index command.
+type bulkOptions struct {
+	// manifest holds the inputs of a new job, the other settings of
+	// the run are filled in by runBulk
+	manifest job
+
+	lang        string
+	user        string
+	concurrency int
+	chunking    chunk.Options
+	window      time.Duration
+}
+
+// runBulk indexes docs as job j, or as a new job with the inputs of
+// opts.manifest when j is nil, announcing the run as indexing what.
+// total is the number of docs, 0 when it is not known up front.
+func runBulk(ctx context.Context, c api.Indexer, j *job, what string, docs <-chan bulkDoc, total int, opts bulkOptions) {
+	if j == nil {
+		manifest := opts.manifest
+		manifest.Lang, manifest.Chunk, manifest.CueWindow = opts.lang, &opts.chunking, opts.window
+		var err error
+		if j, err = newJob(&manifest); err != nil {
+			printErr(fmt.Sprintf("failed to create job manifest: %v", err))
+			return
+		}
+		defer j.close()
+	}
+	printMsg(fmt.Sprintf("Indexing %s as job %s.", what, j.ID))
+
+	summary := indexBulk(ctx, c, j, docs, total, opts.lang, opts.user, opts.concurrency)
+	printBulkSummary(summary, j)
+}
+
+func printIndexResult(res *api.IndexResult, err error, detected langid.Result) {
 	if err != nil {
 		msg := fmt.Sprintf("Failed to index document: %v", err)
 		printErr(msg)
+		printErrHint(err, fmt.Sprintf("Check the document text and its language code, valid codes: %s", strings.Join(validLangs, ", ")))
+	} else if output.format != outputText {
+		rememberEntities(res.Entities)
+		printRenderErr(renderIndexResult(os.Stdout, output, res, detected))
 	} else {
+		rememberEntities(res.Entities)
 		printMsg("Document successfully indexed.")
+		if res.ID != "" {
+			fmt.Printf("ID: %s\n", res.ID)
+		}
+		if detected.Lang != "" {
+			fmt.Printf("Language: %s (detected, %.0f%% confidence)\n", detected.Lang, detected.Confidence*100)
+			if detected.Confidence < unsureLangConfidence {
+				printMsg(fmt.Sprintf("The language is a guess, use '--lang' if the document is not in %q.", detected.Lang))
+			}
+		}
 		printThemes(res.Themes)
 		sentiment := printSentiment(res.Sentiment)
 		classes := printClassifications(res.Entities)
@@ -111,6 +506,14 @@ func printThemes(themes []string) {
 }
 
 func printSentiment(sent string) string {
+	sent = sentimentName(sent)
+	fmt.Printf("Sentiment:\n- %s\n", sent)
+
+	return sent
+}
+
+// sentimentName expands short sentiment codes returned by the server.
+func sentimentName(sent string) string {
 	if sent == "neu" {
 		sent = "neutral"
 	} else if sent == "pos" {
@@ -118,7 +521,6 @@ func printSentiment(sent string) string {
 	} else if sent == "neg" {
 		sent = "negative"
 	}
-	fmt.Printf("Sentiment:\n- %s\n", sent)
 
 	return sent
 }
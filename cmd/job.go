@@ -0,0 +1,246 @@
+package cmd
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+
+	api "github.com/repustate/rcli/api-client/v4"
+	"github.com/repustate/rcli/cmd/chunk"
+)
+
+const (
+	jobsDirname        = "jobs"
+	jobFilename        = "job.json"
+	manifestFilename   = "manifest.jsonl"
+	deadLetterFilename = "deadletter.jsonl"
+
+	statusIndexed = "indexed"
+	statusFailed  = "failed"
+	statusSkipped = "skipped"
+)
+
+// job is a bulk index run, checkpointed in a manifest so that
+// an interrupted run can be resumed without re-sending documents.
+type job struct {
+	ID        string    `json:"id"`
+	Created   time.Time `json:"created"`
+	Patterns  []string  `json:"patterns"`
+	Recursive bool      `json:"recursive"`
+	Lang      string    `json:"lang,omitempty"`
+	// Split is how the standard input was split into documents
+	Split string `json:"split,omitempty"`
+	// Mirror is the identity whose mirror a reindex job replays
+	Mirror string `json:"mirror,omitempty"`
+	// Format is the format of structured files whose every record is
+	// a document, read according to Fields
+	Format string    `json:"format,omitempty"`
+	Fields *fieldMap `json:"fields,omitempty"`
+	// Mbox and Maildir are the mailboxes whose every message is
+	// a document
+	Mbox    []string `json:"mbox,omitempty"`
+	Maildir []string `json:"maildir,omitempty"`
+	// Chunk tells how long documents are split into passages, documents
+	// are indexed whole without it
+	Chunk *chunk.Options `json:"chunk,omitempty"`
+	// CueWindow is the length of the windows of subtitle cues indexed as
+	// a passage, every cue being a passage when 0
+	CueWindow time.Duration `json:"cue_window,omitempty"`
+
+	// content hashes of documents indexed by previous runs
+	done       map[string]bool
+	manifest   *os.File
+	deadLetter *os.File
+}
+
+// jobEntry is a manifest line, recording the outcome of a single document.
+type jobEntry struct {
+	Hash      string       `json:"hash"`
+	Path      string       `json:"path"`
+	Status    string       `json:"status"`
+	ID        string       `json:"id,omitempty"`
+	Themes    []string     `json:"themes,omitempty"`
+	Sentiment string       `json:"sentiment,omitempty"`
+	Entities  []api.Entity `json:"entities,omitempty"`
+	Error     string       `json:"error,omitempty"`
+	Time      time.Time    `json:"time"`
+}
+
+func jobDir(id string) string {
+	return filepath.Join(getConfigDir(), jobsDirname, id)
+}
+
+// newJob creates the manifest of a new bulk index run with the inputs
+// described by j.
+func newJob(j *job) (*job, error) {
+	now := time.Now()
+	j.ID = now.Format("20060102-150405")
+	j.Created = now
+	j.done = map[string]bool{}
+
+	if err := os.MkdirAll(filepath.Join(getConfigDir(), jobsDirname), 0700); err != nil {
+		return nil, err
+	}
+	// runs started within the same second get a numeric suffix
+	base := j.ID
+	for i := 2; ; i++ {
+		err := os.Mkdir(jobDir(j.ID), 0700)
+		if err == nil {
+			break
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		j.ID = fmt.Sprintf("%s-%d", base, i)
+	}
+
+	dir := jobDir(j.ID)
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, jobFilename), data, 0600); err != nil {
+		return nil, err
+	}
+
+	return j, j.open()
+}
+
+// loadJob reopens the manifest of the job with the given id.
+// Documents indexed by earlier runs are remembered so they can be skipped.
+func loadJob(id string) (*job, error) {
+	dir := jobDir(id)
+	data, err := ioutil.ReadFile(filepath.Join(dir, jobFilename))
+	if os.IsNotExist(err) {
+		return nil, errors.Errorf("unknown job %q", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	j := &job{done: map[string]bool{}}
+	if err := json.Unmarshal(data, j); err != nil {
+		return nil, errors.WithMessagef(err, "bad job file of %q", id)
+	}
+
+	f, err := os.Open(filepath.Join(dir, manifestFilename))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if f != nil {
+		defer f.Close()
+		sc := bufio.NewScanner(f)
+		sc.Buffer(nil, 1024*1024)
+		for sc.Scan() {
+			var e jobEntry
+			// a line torn by an abrupt exit is not fatal, the
+			// document is just sent again
+			if json.Unmarshal(sc.Bytes(), &e) != nil {
+				continue
+			}
+			if e.Status == statusIndexed {
+				j.done[e.Hash] = true
+			}
+		}
+		if err := sc.Err(); err != nil {
+			return nil, errors.WithMessagef(err, "failed to read manifest of %q", id)
+		}
+	}
+
+	// failed documents are retried, the dead-letter file only
+	// lists the failures of the latest run
+	return j, j.open()
+}
+
+func (j *job) open() error {
+	dir := jobDir(j.ID)
+
+	var err error
+	j.manifest, err = os.OpenFile(filepath.Join(dir, manifestFilename), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	j.deadLetter, err = os.OpenFile(filepath.Join(dir, deadLetterFilename), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		j.manifest.Close()
+		return err
+	}
+
+	return nil
+}
+
+// isDone reports whether a document with the given content hash was
+// indexed by a previous run of the job.
+func (j *job) isDone(hash string) bool {
+	return j.done[hash]
+}
+
+// record appends the outcome of r to the manifest. Failed documents
+// are also added to the dead-letter file.
+func (j *job) record(r bulkResult) error {
+	e := jobEntry{
+		Hash:   r.Hash,
+		Path:   r.Doc.Path,
+		Status: statusIndexed,
+		Time:   time.Now(),
+	}
+	if r.Err != nil {
+		e.Status = statusFailed
+		e.Error = r.Err.Error()
+	} else {
+		e.ID = r.Res.ID
+		e.Themes = r.Res.Themes
+		e.Sentiment = r.Res.Sentiment
+		e.Entities = r.Res.Entities
+	}
+
+	if err := writeJSONLine(j.manifest, e); err != nil {
+		return err
+	}
+	if r.Err != nil {
+		return writeJSONLine(j.deadLetter, e)
+	}
+
+	return nil
+}
+
+// chunking returns how documents of the job are split into passages.
+func (j *job) chunking() chunk.Options {
+	if j.Chunk == nil {
+		return chunk.Options{}
+	}
+
+	return *j.Chunk
+}
+
+func (j *job) close() {
+	j.manifest.Close()
+	j.deadLetter.Close()
+}
+
+func (j *job) deadLetterPath() string {
+	return filepath.Join(jobDir(j.ID), deadLetterFilename)
+}
+
+func writeJSONLine(f *os.File, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+
+	return err
+}
+
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
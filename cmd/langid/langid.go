@@ -0,0 +1,214 @@
+// Package langid identifies the language of a text offline, among the
+// languages supported by the Repustate demo.
+//
+// Arabic, Chinese and Russian are told apart by their script. Languages
+// written in the Latin script are scored with character n-gram models,
+// built from the samples in samples.go.
+package langid
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// longest character n-grams of the models
+	maxN = 3
+
+	// minimum number of letters of a text to detect its language
+	minLetters = 3
+
+	// minimum number of Latin letters of a text to detect its language,
+	// the n-grams of a few words being as likely to come from names as
+	// from the language of the text
+	minLatinLetters = 40
+
+	// letters of a text after which more are not looked at
+	maxLetters = 2000
+
+	// evidenceScale tempers the likelihood ratios of the models, which
+	// treat overlapping n-grams as independent and so are overconfident
+	evidenceScale = 0.3
+)
+
+// Result is the language of a text.
+type Result struct {
+	// Lang is the language code, empty when the language is unknown
+	Lang string
+	// Confidence is the probability of Lang, between 0 and 1
+	Confidence float64
+}
+
+// scriptLangs are the languages identified by the script they are written in.
+var scriptLangs = map[*unicode.RangeTable]string{
+	unicode.Arabic:   "ar",
+	unicode.Cyrillic: "ru",
+	unicode.Han:      "zh",
+}
+
+type model struct {
+	counts map[string]int
+	total  int
+}
+
+var (
+	models = map[string]*model{}
+	// vocabulary is the number of distinct n-grams of all models
+	vocabulary int
+)
+
+func init() {
+	all := map[string]bool{}
+	for lang, text := range samples {
+		m := &model{counts: map[string]int{}}
+		for _, g := range ngrams(text) {
+			m.counts[g]++
+			m.total++
+			all[g] = true
+		}
+		models[lang] = m
+	}
+	vocabulary = len(all)
+}
+
+// Languages lists the codes of the languages Detect can identify.
+func Languages() []string {
+	var langs []string
+	for _, lang := range scriptLangs {
+		langs = append(langs, lang)
+	}
+	for lang := range models {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+
+	return langs
+}
+
+// Detect identifies the language of text. The result has no language
+// when text is too short or written in an unsupported script, texts in
+// the Latin script needing a sentence or two.
+func Detect(text string) Result {
+	scripts := map[*unicode.RangeTable]int{}
+	letters, kana := 0, 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if letters > maxLetters {
+			break
+		}
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			scripts[unicode.Latin]++
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+		default:
+			for script := range scriptLangs {
+				if unicode.Is(script, r) {
+					scripts[script]++
+				}
+			}
+		}
+	}
+	if letters < minLetters {
+		return Result{}
+	}
+
+	var top *unicode.RangeTable
+	for script, n := range scripts {
+		if top == nil || n > scripts[top] {
+			top = script
+		}
+	}
+	if top == nil {
+		return Result{}
+	}
+	// Japanese mixes Han characters with kana
+	if top == unicode.Han && kana > 0 {
+		return Result{}
+	}
+
+	share := float64(scripts[top]) / float64(min(letters, maxLetters))
+	if top != unicode.Latin {
+		return Result{Lang: scriptLangs[top], Confidence: share}
+	}
+	if scripts[top] < minLatinLetters {
+		return Result{}
+	}
+
+	res := detectLatin(text)
+	res.Confidence *= share
+	return res
+}
+
+// detectLatin scores text with the n-gram model of every language,
+// turning log-likelihoods into probabilities of equally likely languages.
+func detectLatin(text string) Result {
+	grams := ngrams(text)
+	if len(grams) == 0 {
+		return Result{}
+	}
+
+	scores := map[string]float64{}
+	best := ""
+	for lang, m := range models {
+		var score float64
+		for _, g := range grams {
+			score += math.Log(float64(m.counts[g]+1) / float64(m.total+vocabulary))
+		}
+		scores[lang] = score * evidenceScale
+		if best == "" || scores[lang] > scores[best] || (scores[lang] == scores[best] && lang < best) {
+			best = lang
+		}
+	}
+
+	var sum float64
+	for _, score := range scores {
+		sum += math.Exp(score - scores[best])
+	}
+
+	return Result{Lang: best, Confidence: 1 / sum}
+}
+
+// ngrams splits text into words and returns the character n-grams of each,
+// words padded with a space on either side, along with the words themselves,
+// as short function words tell languages apart best.
+func ngrams(text string) []string {
+	var grams []string
+	letters := 0
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	for _, w := range words {
+		w = strings.Trim(w, "'")
+		runes := []rune(" " + w + " ")
+		letters += len(runes) - 2
+		if len(runes) > maxN {
+			grams = append(grams, " "+w+" ")
+		}
+		for n := 1; n <= maxN; n++ {
+			for i := 0; i+n <= len(runes); i++ {
+				if n == 1 && runes[i] == ' ' {
+					continue
+				}
+				grams = append(grams, string(runes[i:i+n]))
+			}
+		}
+		if letters > maxLetters {
+			break
+		}
+	}
+
+	return grams
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
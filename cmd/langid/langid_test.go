@@ -0,0 +1,53 @@
+package langid
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"The committee will publish its report on the new railway line next month.", "en"},
+		{"Der Ausschuss wird seinen Bericht über die neue Bahnstrecke nächsten Monat veröffentlichen.", "de"},
+		{"El comité publicará su informe sobre la nueva línea de ferrocarril el mes que viene.", "es"},
+		{"Le comité publiera son rapport sur la nouvelle ligne de chemin de fer le mois prochain.", "fr"},
+		{"The hotel staff were friendly, but the room was small and the breakfast was disappointing.", "en"},
+		{"Das Personal war freundlich, aber das Zimmer war klein und das Frühstück enttäuschend.", "de"},
+		{"El personal fue amable, pero la habitación era pequeña y el desayuno decepcionante.", "es"},
+		{"Le personnel était aimable, mais la chambre était petite et le petit déjeuner décevant.", "fr"},
+		{"Merkel met Macron in Berlin and they talked about the future of the European Union for hours.", "en"},
+		{"Прогноз погоды на завтра обещает дождь.", "ru"},
+		{"明天的天气预报说会下雨。", "zh"},
+		{"توقعات الطقس للغد تشير إلى هطول أمطار.", "ar"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			res := Detect(tt.text)
+			if res.Lang != tt.want {
+				t.Errorf("Detect(%q) = %s %.2f, want %s", tt.text, res.Lang, res.Confidence, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectShortText(t *testing.T) {
+	// a few words are not enough to tell Latin languages apart, names
+	// weighing as much as the words of the language
+	tests := []string{
+		"I love Paris",
+		"Merkel met Macron in Berlin",
+		"Hola",
+		"OK",
+		"12345 !!",
+		"",
+		// Japanese mixes Han characters with kana
+		"今日はいい天気ですね。",
+	}
+
+	for _, text := range tests {
+		if res := Detect(text); res.Lang != "" {
+			t.Errorf("Detect(%q) = %s %.2f, want no language", text, res.Lang, res.Confidence)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package langid
+
+// samples are the texts the character n-gram models of languages written
+// in the Latin script are built from. They mix everyday, news and business
+// language, product reviews, travel, health and technology, so no single
+// topic dominates a model.
+var samples = map[string]string{
+	"en": `The weather in London was good this morning, and the streets near the river were full of people
+walking to work. The government announced on Tuesday that it would spend more money on schools and
+hospitals over the next three years. Many of the companies that have their offices in the city said
+they were happy with the decision, although some economists warned that the plan could increase the
+national debt. What do you think about the new restaurant that opened last week? I have not been there
+yet, but my friends told me that the food is excellent and the prices are reasonable. The football
+team won their match against their oldest rivals, which made the fans very happy. She said that she
+would come back later because she had to finish her work before the meeting. It is important to
+remember that the weather can change quickly in the mountains, so you should always bring a warm
+jacket with you. The president of the bank explained that interest rates will probably stay low for
+a long time. There are many reasons why people choose to live in the country rather than in a big
+city. This is one of the most beautiful places that I have ever seen. Which of these books would you
+recommend to a student who is interested in history and politics? Our customers expect fast delivery
+and friendly service, and we are working hard to meet their expectations every day. The results of
+the study show that children who read every evening do better at school. They have been waiting for
+the train for more than an hour, and nobody knows when it will arrive.
+
+I bought this phone two months ago and I am still very pleased with it. The battery lasts the whole
+day, the screen is bright enough to read in the sun, and the camera takes sharp pictures even at
+night. The only thing I do not like is the charger, which gets quite hot. Customer support answered
+my questions quickly and politely. Would I buy it again? Probably yes, although it is a little too
+expensive for what it offers. My sister, on the other hand, returned hers after a week because it
+kept turning itself off.
+
+When we arrived at the hotel, the room was not ready, so we left our bags at the reception and went
+for a walk along the beach. The water was warm and clear, and there were only a few families with
+small children. In the evening we had dinner in a small village on the hill, where the owner of the
+restaurant recommended the fish of the day. Everything was fresh and tasty, and the view of the sea
+at sunset was wonderful. The next morning it rained, so we visited the museum and the old castle
+instead of going back to the beach.
+
+Doctors say that regular exercise and a healthy diet are the best ways to avoid many common
+illnesses. You do not need to run a marathon: walking for half an hour every day, taking the stairs
+instead of the lift and eating more fruit and vegetables already make a difference. Sleep is just as
+important, because the body needs time to recover. Those who work at a desk all day should stand up
+and stretch from time to time. If you feel tired for weeks without a reason, it is better to see
+your doctor than to wait until the problem gets worse.
+
+The new version of the software makes it easier to share files with colleagues and to work on the
+same document at the same time. Users can now search their emails, calendars and notes from one
+place, and the application starts much faster than before. Some of the older features have been
+removed, which has annoyed a number of long-time users. The developers promised to listen to their
+feedback and to bring back the most popular tools in the next update, which should be released
+before the end of the year.`,
+
+	"de": `Das Wetter in Berlin war heute Morgen schön, und die Straßen in der Nähe des Flusses waren voller
+Menschen, die zur Arbeit gingen. Die Regierung hat am Dienstag angekündigt, dass sie in den nächsten
+drei Jahren mehr Geld für Schulen und Krankenhäuser ausgeben wird. Viele der Unternehmen, die ihre
+Büros in der Stadt haben, sagten, dass sie mit der Entscheidung zufrieden sind, obwohl einige Ökonomen
+warnten, dass der Plan die Staatsverschuldung erhöhen könnte. Was hältst du von dem neuen Restaurant,
+das letzte Woche eröffnet wurde? Ich war noch nicht dort, aber meine Freunde haben mir erzählt, dass
+das Essen ausgezeichnet ist und die Preise vernünftig sind. Die Fußballmannschaft hat ihr Spiel gegen
+den ältesten Rivalen gewonnen, worüber sich die Fans sehr gefreut haben. Sie sagte, dass sie später
+zurückkommen würde, weil sie ihre Arbeit vor der Besprechung beenden musste. Es ist wichtig, sich
+daran zu erinnern, dass sich das Wetter in den Bergen schnell ändern kann, deshalb sollte man immer
+eine warme Jacke mitnehmen. Der Präsident der Bank erklärte, dass die Zinsen wahrscheinlich noch lange
+niedrig bleiben werden. Es gibt viele Gründe, warum Menschen lieber auf dem Land als in einer großen
+Stadt leben. Das ist einer der schönsten Orte, die ich je gesehen habe. Welches dieser Bücher würdest
+du einem Studenten empfehlen, der sich für Geschichte und Politik interessiert? Unsere Kunden erwarten
+eine schnelle Lieferung und einen freundlichen Service, und wir arbeiten jeden Tag hart daran, ihre
+Erwartungen zu erfüllen. Die Ergebnisse der Studie zeigen, dass Kinder, die jeden Abend lesen, in der
+Schule besser sind. Sie warten schon seit mehr als einer Stunde auf den Zug, und niemand weiß, wann er
+ankommen wird.
+
+Ich habe dieses Handy vor zwei Monaten gekauft und bin immer noch sehr zufrieden damit. Der Akku
+hält den ganzen Tag, der Bildschirm ist hell genug, um in der Sonne zu lesen, und die Kamera macht
+auch nachts scharfe Bilder. Das Einzige, was mir nicht gefällt, ist das Ladegerät, das ziemlich heiß
+wird. Der Kundendienst hat meine Fragen schnell und höflich beantwortet. Würde ich es wieder kaufen?
+Wahrscheinlich ja, obwohl es für das, was es bietet, etwas zu teuer ist. Meine Schwester dagegen hat
+ihres nach einer Woche zurückgegeben, weil es sich immer wieder von selbst ausgeschaltet hat.
+
+Als wir im Hotel ankamen, war das Zimmer noch nicht fertig, also ließen wir unser Gepäck an der
+Rezeption und machten einen Spaziergang am Strand entlang. Das Wasser war warm und klar, und es gab
+nur ein paar Familien mit kleinen Kindern. Am Abend aßen wir in einem kleinen Dorf auf dem Hügel, wo
+uns der Besitzer des Restaurants den Fisch des Tages empfahl. Alles war frisch und lecker, und der
+Blick auf das Meer beim Sonnenuntergang war wunderbar. Am nächsten Morgen regnete es, deshalb
+besuchten wir das Museum und die alte Burg, anstatt wieder an den Strand zu gehen.
+
+Ärzte sagen, dass regelmäßige Bewegung und eine gesunde Ernährung die besten Mittel sind, um viele
+häufige Krankheiten zu vermeiden. Man muss keinen Marathon laufen: Jeden Tag eine halbe Stunde zu
+Fuß gehen, die Treppe statt des Aufzugs nehmen und mehr Obst und Gemüse essen macht schon einen
+Unterschied. Schlaf ist genauso wichtig, weil der Körper Zeit braucht, um sich zu erholen. Wer den
+ganzen Tag am Schreibtisch arbeitet, sollte ab und zu aufstehen und sich strecken. Wenn man sich
+wochenlang ohne Grund müde fühlt, ist es besser, zum Arzt zu gehen, als zu warten, bis das Problem
+schlimmer wird.
+
+Die neue Version der Software macht es einfacher, Dateien mit Kollegen zu teilen und gleichzeitig am
+selben Dokument zu arbeiten. Die Benutzer können jetzt ihre E-Mails, Kalender und Notizen an einem
+Ort durchsuchen, und die Anwendung startet viel schneller als vorher. Einige der älteren Funktionen
+wurden entfernt, was eine Reihe langjähriger Benutzer geärgert hat. Die Entwickler haben versprochen,
+auf ihre Rückmeldungen zu hören und die beliebtesten Werkzeuge mit dem nächsten Update
+zurückzubringen, das noch vor Ende des Jahres erscheinen soll.`,
+
+	"es": `El tiempo en Madrid era bueno esta mañana, y las calles cerca del río estaban llenas de gente que
+caminaba al trabajo. El gobierno anunció el martes que gastará más dinero en escuelas y hospitales
+durante los próximos tres años. Muchas de las empresas que tienen sus oficinas en la ciudad dijeron
+que estaban contentas con la decisión, aunque algunos economistas advirtieron que el plan podría
+aumentar la deuda nacional. ¿Qué piensas del nuevo restaurante que abrió la semana pasada? Todavía no
+he estado allí, pero mis amigos me dijeron que la comida es excelente y los precios son razonables.
+El equipo de fútbol ganó su partido contra sus rivales más antiguos, lo que hizo muy felices a los
+aficionados. Ella dijo que volvería más tarde porque tenía que terminar su trabajo antes de la
+reunión. Es importante recordar que el tiempo puede cambiar rápidamente en las montañas, así que
+siempre debes llevar una chaqueta de abrigo. El presidente del banco explicó que los tipos de interés
+probablemente seguirán bajos durante mucho tiempo. Hay muchas razones por las que la gente prefiere
+vivir en el campo en lugar de en una gran ciudad. Este es uno de los lugares más bonitos que he visto
+nunca. ¿Cuál de estos libros recomendarías a un estudiante que se interesa por la historia y la
+política? Nuestros clientes esperan una entrega rápida y un servicio amable, y trabajamos duro cada
+día para cumplir sus expectativas. Los resultados del estudio muestran que los niños que leen todas
+las noches obtienen mejores notas en la escuela. Llevan más de una hora esperando el tren, y nadie
+sabe cuándo llegará.
+
+Compré este teléfono hace dos meses y todavía estoy muy contento con él. La batería dura todo el
+día, la pantalla es lo bastante brillante para leer al sol y la cámara hace fotos nítidas incluso de
+noche. Lo único que no me gusta es el cargador, que se calienta bastante. El servicio de atención al
+cliente respondió a mis preguntas con rapidez y amabilidad. ¿Lo volvería a comprar? Probablemente
+sí, aunque es un poco caro para lo que ofrece. Mi hermana, en cambio, devolvió el suyo después de
+una semana porque se apagaba solo una y otra vez.
+
+Cuando llegamos al hotel, la habitación no estaba lista, así que dejamos las maletas en la recepción
+y fuimos a dar un paseo por la playa. El agua estaba templada y clara, y solo había unas pocas
+familias con niños pequeños. Por la noche cenamos en un pueblo pequeño en la colina, donde el dueño
+del restaurante nos recomendó el pescado del día. Todo estaba fresco y sabroso, y la vista del mar al
+atardecer era maravillosa. A la mañana siguiente llovió, por eso visitamos el museo y el castillo
+antiguo en vez de volver a la playa.
+
+Los médicos dicen que el ejercicio regular y una dieta sana son las mejores formas de evitar muchas
+enfermedades comunes. No hace falta correr un maratón: caminar media hora cada día, subir por las
+escaleras en lugar de usar el ascensor y comer más fruta y verdura ya marcan la diferencia. El sueño
+es igual de importante, porque el cuerpo necesita tiempo para recuperarse. Quienes trabajan todo el
+día sentados deberían levantarse y estirarse de vez en cuando. Si te sientes cansado durante semanas
+sin motivo, es mejor ir al médico que esperar a que el problema empeore.
+
+La nueva versión del programa hace más fácil compartir archivos con los compañeros y trabajar en el
+mismo documento a la vez. Ahora los usuarios pueden buscar sus correos, calendarios y notas desde un
+solo lugar, y la aplicación se abre mucho más rápido que antes. Se han eliminado algunas de las
+funciones más antiguas, lo que ha molestado a varios usuarios de toda la vida. Los desarrolladores
+prometieron escuchar sus opiniones y recuperar las herramientas más populares en la próxima
+actualización, que debería publicarse antes de que termine el año.`,
+
+	"fr": `Le temps à Paris était beau ce matin, et les rues près de la rivière étaient pleines de gens qui
+allaient au travail. Le gouvernement a annoncé mardi qu'il dépenserait plus d'argent pour les écoles
+et les hôpitaux au cours des trois prochaines années. Beaucoup des entreprises qui ont leurs bureaux
+dans la ville ont dit qu'elles étaient satisfaites de la décision, bien que certains économistes aient
+averti que le plan pourrait augmenter la dette nationale. Que penses-tu du nouveau restaurant qui a
+ouvert la semaine dernière? Je n'y suis pas encore allé, mais mes amis m'ont dit que la cuisine est
+excellente et que les prix sont raisonnables. L'équipe de football a gagné son match contre ses plus
+anciens rivaux, ce qui a rendu les supporters très heureux. Elle a dit qu'elle reviendrait plus tard
+parce qu'elle devait finir son travail avant la réunion. Il est important de se rappeler que le temps
+peut changer rapidement en montagne, alors il faut toujours emporter une veste chaude. Le président de
+la banque a expliqué que les taux d'intérêt resteront probablement bas pendant longtemps. Il y a
+beaucoup de raisons pour lesquelles les gens choisissent de vivre à la campagne plutôt que dans une
+grande ville. C'est l'un des plus beaux endroits que j'aie jamais vus. Lequel de ces livres
+recommanderais-tu à un étudiant qui s'intéresse à l'histoire et à la politique? Nos clients attendent
+une livraison rapide et un service aimable, et nous travaillons dur chaque jour pour répondre à leurs
+attentes. Les résultats de l'étude montrent que les enfants qui lisent tous les soirs réussissent
+mieux à l'école. Ils attendent le train depuis plus d'une heure, et personne ne sait quand il
+arrivera.
+
+J'ai acheté ce téléphone il y a deux mois et j'en suis toujours très content. La batterie tient
+toute la journée, l'écran est assez lumineux pour lire au soleil et l'appareil photo prend des
+images nettes même la nuit. La seule chose qui ne me plaît pas, c'est le chargeur, qui chauffe
+beaucoup. Le service client a répondu à mes questions rapidement et poliment. Est-ce que je
+l'achèterais de nouveau? Probablement oui, même s'il est un peu trop cher pour ce qu'il offre. Ma
+sœur, en revanche, a rendu le sien au bout d'une semaine parce qu'il s'éteignait tout seul.
+
+Quand nous sommes arrivés à l'hôtel, la chambre n'était pas prête, alors nous avons laissé nos
+valises à la réception et nous sommes allés nous promener le long de la plage. L'eau était chaude et
+claire, et il n'y avait que quelques familles avec de jeunes enfants. Le soir, nous avons dîné dans
+un petit village sur la colline, où le patron du restaurant nous a conseillé le poisson du jour. Tout
+était frais et délicieux, et la vue sur la mer au coucher du soleil était magnifique. Le lendemain
+matin, il a plu, donc nous avons visité le musée et le vieux château au lieu de retourner à la plage.
+
+Les médecins disent qu'une activité physique régulière et une alimentation saine sont les meilleurs
+moyens d'éviter beaucoup de maladies courantes. Pas besoin de courir un marathon: marcher une
+demi-heure chaque jour, prendre l'escalier plutôt que l'ascenseur et manger plus de fruits et de
+légumes font déjà une différence. Le sommeil est tout aussi important, car le corps a besoin de temps
+pour récupérer. Ceux qui travaillent toute la journée à un bureau devraient se lever et s'étirer de
+temps en temps. Si vous vous sentez fatigué pendant des semaines sans raison, il vaut mieux voir
+votre médecin que d'attendre que le problème s'aggrave.
+
+La nouvelle version du logiciel permet de partager plus facilement des fichiers avec ses collègues
+et de travailler sur le même document en même temps. Les utilisateurs peuvent désormais chercher
+dans leurs courriels, leurs agendas et leurs notes depuis un seul endroit, et l'application démarre
+beaucoup plus vite qu'avant. Certaines des anciennes fonctions ont été supprimées, ce qui a agacé un
+bon nombre d'utilisateurs de longue date. Les développeurs ont promis d'écouter leurs remarques et de
+rétablir les outils les plus populaires dans la prochaine mise à jour, qui devrait sortir avant la
+fin de l'année.`,
+}
@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/repustate/rcli/cmd/email"
+)
+
+const (
+	mboxFlag    = "mbox"
+	maildirFlag = "maildir"
+
+	// metadata of indexed email messages
+	metaSubject = "subject"
+	metaFrom    = "from"
+	metaDate    = "date"
+)
+
+// streamMail reads the messages of mbox files and Maildir folders as bulk
+// documents, stdinPath being an mbox file on the standard input. Messages
+// which cannot be read, and mailboxes which cannot be read at all, are
+// sent as documents failing with the reason. Reading stops when ctx is done.
+func streamMail(ctx context.Context, mboxes, maildirs []string) <-chan bulkDoc {
+	ch := make(chan bulkDoc)
+	send := func(doc bulkDoc) bool {
+		select {
+		case ch <- doc:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	go func() {
+		defer close(ch)
+		for _, input := range mboxes {
+			name := input
+			if input == stdinPath {
+				name = "stdin"
+			}
+			err := readMbox(input, name, send)
+			if err == errStopped {
+				return
+			}
+			if err != nil && !send(bulkDoc{Path: name, Err: err}) {
+				return
+			}
+		}
+		for _, dir := range maildirs {
+			err := readMaildir(dir, send)
+			if err == errStopped {
+				return
+			}
+			if err != nil && !send(bulkDoc{Path: dir, Err: err}) {
+				return
+			}
+		}
+	}()
+
+	return ch
+}
+
+// describeMailboxes names the mailboxes of an index run in messages.
+func describeMailboxes(mboxes, maildirs []string) string {
+	switch {
+	case len(mboxes) == 1 && len(maildirs) == 0 && mboxes[0] == stdinPath:
+		return "the standard input"
+	case len(mboxes)+len(maildirs) == 1:
+		return strings.Join(append(mboxes, maildirs...), "")
+	}
+	return fmt.Sprintf("%d mailboxes", len(mboxes)+len(maildirs))
+}
+
+// readMbox sends the messages of an mbox file, numbered from 1.
+func readMbox(input, name string, send func(bulkDoc) bool) error {
+	var r io.Reader = os.Stdin
+	if input != stdinPath {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	mr := email.NewMboxReader(r)
+	for n := 1; ; n++ {
+		path := fmt.Sprintf("%s:%d", name, n)
+		data, err := mr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if errors.Is(err, email.ErrTooLarge) {
+			if !send(bulkDoc{Path: path, Err: err}) {
+				return errStopped
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if !send(mailDoc(path, data)) {
+			return errStopped
+		}
+	}
+}
+
+// readMaildir sends the messages of a Maildir folder and of its
+// subfolders, in the order of their file names. Messages still being
+// delivered to tmp are left out.
+func readMaildir(dir string, send func(bulkDoc) bool) error {
+	var files []string
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == "tmp" && p != dir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// Maildir++ subfolders are hidden directories, message files never
+		// are hidden
+		parent := filepath.Base(filepath.Dir(p))
+		if (parent == "cur" || parent == "new") && info.Mode().IsRegular() && !strings.HasPrefix(info.Name(), ".") {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		if _, err := os.Stat(filepath.Join(dir, "cur")); os.IsNotExist(err) {
+			return errors.New("not a Maildir folder, it has no cur directory")
+		}
+	}
+
+	for _, f := range files {
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			if !send(bulkDoc{Path: f, Err: err}) {
+				return errStopped
+			}
+			continue
+		}
+		if !send(mailDoc(f, data)) {
+			return errStopped
+		}
+	}
+
+	return nil
+}
+
+// mailDoc turns a message into a document, its subject heading the text.
+func mailDoc(path string, data []byte) bulkDoc {
+	doc := bulkDoc{Path: path}
+
+	msg, err := email.Parse(data)
+	if err != nil {
+		doc.Err = err
+		return doc
+	}
+
+	doc.Text = strings.TrimSpace(msg.Subject + "\n\n" + msg.Text)
+	if doc.Text == "" {
+		doc.Err = errors.New("the message has no text")
+		return doc
+	}
+
+	doc.Metadata = map[string]string{}
+	if msg.Subject != "" {
+		doc.Metadata[metaSubject] = msg.Subject
+	}
+	if msg.From != "" {
+		doc.Metadata[metaFrom] = msg.From
+	}
+	if !msg.Date.IsZero() {
+		doc.Metadata[metaDate] = msg.Date.Format(time.RFC3339)
+	}
+
+	return doc
+}
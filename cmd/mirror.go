@@ -0,0 +1,295 @@
+package cmd
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+
+	api "github.com/repustate/rcli/api-client/v4"
+)
+
+const mirrorDirname = "mirror"
+
+// mirrorRecord is the state of a mirrored document after a successful
+// index, update or delete. A mirror keeps the latest record of every
+// document not deleted.
+type mirrorRecord struct {
+	ID string `json:"id,omitempty"`
+	// OwnID is set when ID was chosen by rcli rather than the server
+	OwnID    bool              `json:"own_id,omitempty"`
+	Hash     string            `json:"hash,omitempty"`
+	Text     string            `json:"text,omitempty"`
+	Lang     string            `json:"lang,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Source   string            `json:"source,omitempty"`
+	Time     time.Time         `json:"time"`
+	Result   *api.IndexResult  `json:"result,omitempty"`
+	Deleted  bool              `json:"deleted,omitempty"`
+}
+
+// key identifies the document of r, by content for servers which do
+// not return document ids.
+func (r mirrorRecord) key() string {
+	if r.ID != "" {
+		return r.ID
+	}
+	return r.Hash
+}
+
+var (
+	// mirrorDocsBucket holds the records of the documents of a mirror,
+	// by the sequence number of their first index
+	mirrorDocsBucket = []byte("documents")
+	// mirrorKeysBucket holds the sequence numbers of the documents of a
+	// mirror, by their key
+	mirrorKeysBucket = []byte("keys")
+)
+
+const (
+	// mirrorBatchSize is the number of records walkMirror reads at once
+	mirrorBatchSize = 100
+	// mirrorLockTimeout is how long to wait for another rcli process
+	// to release a mirror
+	mirrorLockTimeout = 10 * time.Second
+)
+
+// mirrorMu serializes the accesses of concurrent bulk index workers,
+// a mirror database is opened for a single access at a time
+var mirrorMu sync.Mutex
+
+// mirrorPath is the mirror database of the identity with the given UUID.
+func mirrorPath(user string) string {
+	return filepath.Join(getConfigDir(), mirrorDirname, user+".db")
+}
+
+// withMirror runs fn with the mirror database of user, which is created
+// unless readOnly is set. Without a mirror fn is not run.
+func withMirror(user string, readOnly bool, fn func(tx *bolt.Tx) error) error {
+	mirrorMu.Lock()
+	defer mirrorMu.Unlock()
+
+	if readOnly {
+		if _, err := os.Stat(mirrorPath(user)); os.IsNotExist(err) {
+			return nil
+		}
+	} else if err := os.MkdirAll(filepath.Dir(mirrorPath(user)), 0700); err != nil {
+		return err
+	}
+	db, err := bolt.Open(mirrorPath(user), 0600, &bolt.Options{Timeout: mirrorLockTimeout, ReadOnly: readOnly})
+	if err != nil {
+		return errors.WithMessagef(err, "failed to open mirror %s", mirrorPath(user))
+	}
+	defer db.Close()
+
+	if readOnly {
+		return db.View(fn)
+	}
+	return db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{mirrorDocsBucket, mirrorKeysBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return fn(tx)
+	})
+}
+
+// putMirror stores records in the mirror of user, replacing the earlier
+// records of their documents.
+func putMirror(user string, recs ...mirrorRecord) error {
+	return withMirror(user, false, func(tx *bolt.Tx) error {
+		docs, keys := tx.Bucket(mirrorDocsBucket), tx.Bucket(mirrorKeysBucket)
+		for _, r := range recs {
+			key := []byte(r.key())
+			if len(key) == 0 {
+				continue
+			}
+			seq := keys.Get(key)
+			if r.Deleted {
+				if seq != nil {
+					if err := docs.Delete(seq); err != nil {
+						return err
+					}
+				}
+				if err := keys.Delete(key); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if seq != nil {
+				// updates keep the metadata and id of the document
+				var prev mirrorRecord
+				if json.Unmarshal(docs.Get(seq), &prev) == nil {
+					if r.Metadata == nil {
+						r.Metadata = prev.Metadata
+					}
+					r.OwnID = r.OwnID || prev.OwnID
+				}
+			} else {
+				n, err := docs.NextSequence()
+				if err != nil {
+					return err
+				}
+				seq = make([]byte, 8)
+				binary.BigEndian.PutUint64(seq, n)
+				if err := keys.Put(key, seq); err != nil {
+					return err
+				}
+			}
+
+			data, err := json.Marshal(r)
+			if err != nil {
+				return err
+			}
+			if err := docs.Put(seq, data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// countMirror returns the number of documents in the mirror of user.
+func countMirror(user string) (int, error) {
+	n := 0
+	err := withMirror(user, true, func(tx *bolt.Tx) error {
+		if docs := tx.Bucket(mirrorDocsBucket); docs != nil {
+			n = docs.Stats().KeyN
+		}
+		return nil
+	})
+
+	return n, err
+}
+
+// walkMirror calls fn with the documents in the mirror of user, in the
+// order they were first indexed, until fn returns false. The documents
+// are read mirrorBatchSize at a time, fn may update the mirror; documents
+// first indexed after the walk started are left out.
+func walkMirror(user string, fn func(r mirrorRecord) bool) error {
+	var last uint64
+	next := make([]byte, 8)
+	for {
+		var batch []mirrorRecord
+		err := withMirror(user, true, func(tx *bolt.Tx) error {
+			docs := tx.Bucket(mirrorDocsBucket)
+			if docs == nil {
+				return nil
+			}
+			if last == 0 {
+				last = docs.Sequence()
+			}
+			c := docs.Cursor()
+			for k, v := c.Seek(next); k != nil && len(batch) < mirrorBatchSize; k, v = c.Next() {
+				if binary.BigEndian.Uint64(k) > last {
+					break
+				}
+				var r mirrorRecord
+				if err := json.Unmarshal(v, &r); err != nil {
+					return errors.WithMessagef(err, "bad record in mirror %s", mirrorPath(user))
+				}
+				batch = append(batch, r)
+				binary.BigEndian.PutUint64(next, binary.BigEndian.Uint64(k)+1)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, r := range batch {
+			if !fn(r) {
+				return nil
+			}
+		}
+		if len(batch) < mirrorBatchSize {
+			return nil
+		}
+	}
+}
+
+type sourceKey struct{}
+
+// withSource tells the mirror where the text indexed with ctx comes from.
+func withSource(ctx context.Context, source string) context.Context {
+	return context.WithValue(ctx, sourceKey{}, source)
+}
+
+func sourceOf(ctx context.Context) string {
+	source, _ := ctx.Value(sourceKey{}).(string)
+	return source
+}
+
+// newMirroringService records every document successfully indexed, updated
+// or deleted through next in the mirror of its identity.
+func newMirroringService(next api.Service) api.Service {
+	return &mirroringService{Service: next}
+}
+
+type mirroringService struct {
+	api.Service
+
+	warned sync.Once
+}
+
+func (s *mirroringService) record(user string, r mirrorRecord) {
+	r.Time = time.Now()
+	if err := putMirror(user, r); err != nil {
+		s.warned.Do(func() {
+			printErr(fmt.Sprintf("Failed to update the local mirror: %v", err))
+		})
+	}
+}
+
+func (s *mirroringService) IndexContext(ctx context.Context, text, lang, user string, opts ...api.IndexOption) (*api.IndexResult, error) {
+	res, err := s.Service.IndexContext(ctx, text, lang, user, opts...)
+	if err == nil {
+		o := api.NewIndexOptions(opts...)
+		s.record(user, mirrorRecord{
+			ID:       res.ID,
+			OwnID:    o.ID != "" && o.ID == res.ID,
+			Hash:     contentHash([]byte(text)),
+			Text:     text,
+			Lang:     lang,
+			Metadata: o.Metadata,
+			Source:   sourceOf(ctx),
+			Result:   res,
+		})
+	}
+
+	return res, err
+}
+
+func (s *mirroringService) UpdateContext(ctx context.Context, id, text, lang, user string) (*api.IndexResult, error) {
+	res, err := s.Service.UpdateContext(ctx, id, text, lang, user)
+	if err == nil {
+		s.record(user, mirrorRecord{
+			ID:     id,
+			Hash:   contentHash([]byte(text)),
+			Text:   text,
+			Lang:   lang,
+			Source: sourceOf(ctx),
+			Result: res,
+		})
+	}
+
+	return res, err
+}
+
+func (s *mirroringService) DeleteContext(ctx context.Context, id, user string) error {
+	err := s.Service.DeleteContext(ctx, id, user)
+	if err == nil {
+		s.record(user, mirrorRecord{ID: id, Deleted: true})
+	}
+
+	return err
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// mirrorKeys returns the keys of the documents in the mirror of user.
+func mirrorKeys(t *testing.T, user string) []string {
+	t.Helper()
+	var keys []string
+	err := walkMirror(user, func(r mirrorRecord) bool {
+		keys = append(keys, r.key())
+		return true
+	})
+	if err != nil {
+		t.Fatalf("walkMirror() error = %v", err)
+	}
+
+	return keys
+}
+
+func TestMirror(t *testing.T) {
+	setenv(t, "XDG_CONFIG_HOME", tempDir(t))
+
+	if n, err := countMirror("ann"); n != 0 || err != nil {
+		t.Fatalf("countMirror() without a mirror = %d, %v", n, err)
+	}
+	if keys := mirrorKeys(t, "ann"); keys != nil {
+		t.Fatalf("walkMirror() without a mirror = %v", keys)
+	}
+
+	err := putMirror("ann",
+		mirrorRecord{ID: "a", Text: "First.", Metadata: map[string]string{"author": "ann"}},
+		mirrorRecord{ID: "b", Text: "Second."},
+		mirrorRecord{ID: "c", Text: "Third."},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// updates keep the place, metadata and id of the document
+	if err := putMirror("ann", mirrorRecord{ID: "a", Text: "First, updated."}, mirrorRecord{ID: "b", Deleted: true}); err != nil {
+		t.Fatal(err)
+	}
+	// deleted documents indexed again come last
+	if err := putMirror("ann", mirrorRecord{ID: "d", Text: "Fourth."}, mirrorRecord{ID: "b", Text: "Second, again."}); err != nil {
+		t.Fatal(err)
+	}
+	if err := putMirror("bob", mirrorRecord{ID: "e", Text: "Other."}); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := countMirror("ann"); n != 4 || err != nil {
+		t.Errorf("countMirror() = %d, %v, want 4", n, err)
+	}
+	if keys, want := mirrorKeys(t, "ann"), []string{"a", "c", "d", "b"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("walkMirror() keys = %v, want %v", keys, want)
+	}
+	var first mirrorRecord
+	walkMirror("ann", func(r mirrorRecord) bool {
+		first = r
+		return false
+	})
+	if first.Text != "First, updated." || first.Metadata["author"] != "ann" {
+		t.Errorf("updated record = %+v", first)
+	}
+}
+
+func TestMirrorWalkUpdates(t *testing.T) {
+	setenv(t, "XDG_CONFIG_HOME", tempDir(t))
+
+	const n = 2*mirrorBatchSize + 10
+	for i := 0; i < n; i++ {
+		if err := putMirror("ann", mirrorRecord{ID: fmt.Sprintf("old-%d", i), Text: "Text."}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a reindex into the same identity replaces every document it walks
+	walked := 0
+	err := walkMirror("ann", func(r mirrorRecord) bool {
+		walked++
+		if err := putMirror("ann", mirrorRecord{ID: "new-" + r.ID, Text: r.Text}, mirrorRecord{ID: r.ID, Deleted: true}); err != nil {
+			t.Fatal(err)
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if walked != n {
+		t.Errorf("walkMirror() walked %d documents, want %d", walked, n)
+	}
+	if count, err := countMirror("ann"); count != n || err != nil {
+		t.Errorf("countMirror() = %d, %v, want %d", count, err, n)
+	}
+}
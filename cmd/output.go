@@ -0,0 +1,434 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	api "github.com/repustate/rcli/api-client/v4"
+	"github.com/repustate/rcli/cmd/langid"
+)
+
+const (
+	outputFlag   = "output"
+	templateFlag = "template"
+
+	outputText     = "text"
+	outputJSON     = "json"
+	outputJSONL    = "jsonl"
+	outputYAML     = "yaml"
+	outputCSV      = "csv"
+	outputTable    = "table"
+	outputTemplate = "template"
+
+	// outputSchemaVersion is bumped on any incompatible change
+	// of the machine-readable output, see docs/output-schema.md
+	outputSchemaVersion = 1
+
+	kindIndexResult  = "index_result"
+	kindSearchResult = "search_result"
+	kindDocument     = "document"
+	kindBulkIndex    = "bulk_index_result"
+	kindDeleted      = "delete_result"
+
+	// maximum text length shown in a table cell
+	maxCellLen = 60
+)
+
+var (
+	outputFormats = []string{
+		outputText,
+		outputJSON,
+		outputJSONL,
+		outputYAML,
+		outputCSV,
+		outputTable,
+		outputTemplate,
+	}
+
+	// output is populated from global flags before any subcommand runs
+	output = outputOptions{format: outputText}
+)
+
+type outputOptions struct {
+	format string
+	tmpl   *template.Template
+}
+
+// machine reports whether results are rendered for other programs
+// rather than humans.
+func (o outputOptions) machine() bool {
+	return o.format != outputText && o.format != outputTable
+}
+
+func newOutputOptions(format, tmpl string) (outputOptions, error) {
+	o := outputOptions{format: format}
+
+	switch format {
+	case outputText, outputJSON, outputJSONL, outputYAML, outputCSV, outputTable:
+	case outputTemplate:
+		if tmpl == "" {
+			return o, errors.Errorf("'--%s' is required with '--%s=%s'", templateFlag, outputFlag, outputTemplate)
+		}
+		t, err := template.New("output").Funcs(templateFuncs).Parse(tmpl)
+		if err != nil {
+			return o, errors.WithMessage(err, "bad output template")
+		}
+		o.tmpl = t
+	default:
+		return o, errors.Errorf("unknown output format %q, valid formats: %s", format, strings.Join(outputFormats, ", "))
+	}
+
+	return o, nil
+}
+
+var templateFuncs = template.FuncMap{
+	"join": strings.Join,
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// envelope wraps every machine-readable output record.
+type envelope struct {
+	SchemaVersion int    `json:"schema_version" yaml:"schema_version"`
+	Kind          string `json:"kind" yaml:"kind"`
+	Source        string `json:"source,omitempty" yaml:"source,omitempty"`
+	Error         string `json:"error,omitempty" yaml:"error,omitempty"`
+	// Language is set when rcli detected the language of the document
+	Language *languageOutput `json:"language,omitempty" yaml:"language,omitempty"`
+	Data     interface{}     `json:"data,omitempty" yaml:"data,omitempty"`
+}
+
+// languageOutput is the language detected for an indexed document.
+type languageOutput struct {
+	Code       string  `json:"code" yaml:"code"`
+	Confidence float64 `json:"confidence" yaml:"confidence"`
+}
+
+func newLanguageOutput(detected langid.Result) *languageOutput {
+	if detected.Lang == "" {
+		return nil
+	}
+	// two decimals are as precise as the detection
+	confidence := math.Round(detected.Confidence*100) / 100
+
+	return &languageOutput{Code: detected.Lang, Confidence: confidence}
+}
+
+func newEnvelope(kind string, data interface{}) envelope {
+	return envelope{
+		SchemaVersion: outputSchemaVersion,
+		Kind:          kind,
+		Data:          data,
+	}
+}
+
+// bulkOutput is the machine-readable form of a bulk index run.
+type bulkOutput struct {
+	Job       string          `json:"job" yaml:"job"`
+	Total     int             `json:"total" yaml:"total"`
+	Indexed   int             `json:"indexed" yaml:"indexed"`
+	Skipped   int             `json:"skipped" yaml:"skipped"`
+	Failed    int             `json:"failed" yaml:"failed"`
+	Split     int             `json:"split,omitempty" yaml:"split,omitempty"`
+	Themes    map[string]int  `json:"themes" yaml:"themes"`
+	Sentiment map[string]int  `json:"sentiment" yaml:"sentiment"`
+	Languages map[string]int  `json:"languages,omitempty" yaml:"languages,omitempty"`
+	Documents []bulkDocOutput `json:"documents" yaml:"documents"`
+}
+
+type bulkDocOutput struct {
+	Source   string            `json:"source" yaml:"source"`
+	Status   string            `json:"status" yaml:"status"`
+	Error    string            `json:"error,omitempty" yaml:"error,omitempty"`
+	Language *languageOutput   `json:"language,omitempty" yaml:"language,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	Result   *api.IndexResult  `json:"result,omitempty" yaml:"result,omitempty"`
+}
+
+func newBulkDocOutput(r bulkResult) bulkDocOutput {
+	d := bulkDocOutput{Source: r.Doc.Path, Status: statusIndexed, Language: newLanguageOutput(r.Lang), Metadata: r.Doc.Metadata, Result: r.Res}
+	if r.Skipped {
+		d.Status = statusSkipped
+	} else if r.Err != nil {
+		d.Status = statusFailed
+		d.Error = r.Err.Error()
+	}
+
+	return d
+}
+
+func newBulkOutput(s *bulkSummary, j *job) bulkOutput {
+	o := bulkOutput{
+		Job:       j.ID,
+		Total:     s.Total,
+		Indexed:   s.Indexed,
+		Skipped:   s.Skipped,
+		Failed:    len(s.Failed),
+		Split:     s.Split,
+		Themes:    s.Themes,
+		Sentiment: s.Sentiment,
+		Languages: s.Languages,
+	}
+	for _, r := range s.Results {
+		o.Documents = append(o.Documents, newBulkDocOutput(r))
+	}
+
+	return o
+}
+
+func renderIndexResult(w io.Writer, o outputOptions, res *api.IndexResult, detected langid.Result) error {
+	e := newEnvelope(kindIndexResult, res)
+	e.Language = newLanguageOutput(detected)
+
+	switch o.format {
+	case outputJSON, outputJSONL:
+		return writeJSON(w, e, o.format == outputJSON)
+	case outputYAML:
+		return writeYAML(w, e)
+	case outputTemplate:
+		return o.tmpl.Execute(w, res)
+	case outputCSV:
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"themes", "sentiment", "entities", "id"})
+		cw.Write([]string{strings.Join(res.Themes, ";"), res.Sentiment, formatEntities(res.Entities), res.ID})
+		cw.Flush()
+		return cw.Error()
+	case outputTable:
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tTHEMES\tSENTIMENT\tENTITIES")
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", res.ID, strings.Join(res.Themes, ", "), sentimentName(res.Sentiment), truncate(formatEntities(res.Entities)))
+		return tw.Flush()
+	}
+
+	return errors.Errorf("unsupported output format %q", o.format)
+}
+
+func renderSearchResult(w io.Writer, o outputOptions, res *api.SearchResult) error {
+	switch o.format {
+	case outputJSON:
+		return writeJSON(w, newEnvelope(kindSearchResult, res), true)
+	case outputJSONL:
+		for i := range res.Documents {
+			if err := writeJSON(w, newEnvelope(kindDocument, &res.Documents[i]), false); err != nil {
+				return err
+			}
+		}
+		return nil
+	case outputYAML:
+		return writeYAML(w, newEnvelope(kindSearchResult, res))
+	case outputTemplate:
+		return o.tmpl.Execute(w, res)
+	case outputCSV:
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"text", "entities", "id", "metadata"})
+		for _, doc := range res.Documents {
+			cw.Write([]string{doc.Text, formatEntities(doc.Entities), doc.ID, formatMetadata(doc.Metadata)})
+		}
+		cw.Flush()
+		return cw.Error()
+	case outputTable:
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "#\tID\tTEXT\tENTITIES")
+		for i, doc := range res.Documents {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", i+1, doc.ID, truncate(doc.Text), truncate(formatEntities(doc.Entities)))
+		}
+		return tw.Flush()
+	}
+
+	return errors.Errorf("unsupported output format %q", o.format)
+}
+
+func renderDocument(w io.Writer, o outputOptions, doc *api.Document) error {
+	switch o.format {
+	case outputJSON, outputJSONL:
+		return writeJSON(w, newEnvelope(kindDocument, doc), o.format == outputJSON)
+	case outputYAML:
+		return writeYAML(w, newEnvelope(kindDocument, doc))
+	case outputTemplate:
+		return o.tmpl.Execute(w, doc)
+	case outputCSV:
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"text", "entities", "id", "metadata"})
+		cw.Write([]string{doc.Text, formatEntities(doc.Entities), doc.ID, formatMetadata(doc.Metadata)})
+		cw.Flush()
+		return cw.Error()
+	case outputTable:
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tTEXT\tENTITIES")
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", doc.ID, truncate(doc.Text), truncate(formatEntities(doc.Entities)))
+		return tw.Flush()
+	}
+
+	return errors.Errorf("unsupported output format %q", o.format)
+}
+
+// deleteOutput is the machine-readable confirmation of a deleted document.
+type deleteOutput struct {
+	ID string `json:"id" yaml:"id"`
+}
+
+func renderDeleteResult(w io.Writer, o outputOptions, id string) error {
+	out := deleteOutput{ID: id}
+
+	switch o.format {
+	case outputJSON, outputJSONL:
+		return writeJSON(w, newEnvelope(kindDeleted, out), o.format == outputJSON)
+	case outputYAML:
+		return writeYAML(w, newEnvelope(kindDeleted, out))
+	case outputTemplate:
+		return o.tmpl.Execute(w, out)
+	case outputCSV:
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"id"})
+		cw.Write([]string{id})
+		cw.Flush()
+		return cw.Error()
+	case outputTable:
+		_, err := fmt.Fprintf(w, "ID\n%s\n", id)
+		return err
+	}
+
+	return errors.Errorf("unsupported output format %q", o.format)
+}
+
+// renderBulkResult writes the record of a single document of a bulk run,
+// only the jsonl format streams documents as they are indexed.
+func renderBulkResult(w io.Writer, o outputOptions, r bulkResult) error {
+	if o.format != outputJSONL {
+		return nil
+	}
+
+	d := newBulkDocOutput(r)
+	e := newEnvelope(kindIndexResult, d.Result)
+	e.Source = d.Source
+	e.Error = d.Error
+	e.Language = d.Language
+
+	return writeJSON(w, e, false)
+}
+
+func renderBulkSummary(w io.Writer, o outputOptions, s *bulkSummary, j *job) error {
+	out := newBulkOutput(s, j)
+
+	switch o.format {
+	case outputJSON:
+		return writeJSON(w, newEnvelope(kindBulkIndex, out), true)
+	case outputJSONL:
+		// documents were already streamed by renderBulkResult
+		return nil
+	case outputYAML:
+		return writeYAML(w, newEnvelope(kindBulkIndex, out))
+	case outputTemplate:
+		return o.tmpl.Execute(w, out)
+	case outputCSV:
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"source", "status", "themes", "sentiment", "entities", "error", "id"})
+		for _, d := range out.Documents {
+			row := []string{d.Source, d.Status, "", "", "", d.Error, ""}
+			if d.Result != nil {
+				row[2] = strings.Join(d.Result.Themes, ";")
+				row[3] = d.Result.Sentiment
+				row[4] = formatEntities(d.Result.Entities)
+				row[6] = d.Result.ID
+			}
+			cw.Write(row)
+		}
+		cw.Flush()
+		return cw.Error()
+	case outputTable:
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "SOURCE\tSTATUS\tID\tTHEMES\tSENTIMENT")
+		for _, d := range out.Documents {
+			id, themes, sent := "", "", ""
+			if d.Result != nil {
+				id = d.Result.ID
+				themes = strings.Join(d.Result.Themes, ", ")
+				sent = sentimentName(d.Result.Sentiment)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", d.Source, d.Status, id, themes, sent)
+		}
+		return tw.Flush()
+	}
+
+	return errors.Errorf("unsupported output format %q", o.format)
+}
+
+func writeJSON(w io.Writer, v interface{}, indent bool) error {
+	enc := json.NewEncoder(w)
+	if indent {
+		enc.SetIndent("", "  ")
+	}
+
+	return enc.Encode(v)
+}
+
+func writeYAML(w io.Writer, v interface{}) error {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+
+	return err
+}
+
+// formatEntities flattens entities into a single value,
+// e.g. "Paris (Location.city); Macron (Person.politician|Person.leader)".
+func formatEntities(entities []api.Entity) string {
+	parts := make([]string, len(entities))
+	for i, e := range entities {
+		parts[i] = fmt.Sprintf("%s (%s)", e.Title, strings.Join(e.Classifications, "|"))
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+// formatMetadata joins metadata fields into a single cell, ordered by name.
+func formatMetadata(md map[string]string) string {
+	parts := make([]string, 0, len(md))
+	for _, k := range sortedKeys(md) {
+		parts = append(parts, k+"="+md[k])
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// truncate shortens s to a single line fitting a table cell.
+func truncate(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	r := []rune(s)
+	if len(r) > maxCellLen {
+		return string(r[:maxCellLen-3]) + "..."
+	}
+
+	return s
+}
+
+// printRenderErr reports a failure to render results in the selected format.
+func printRenderErr(err error) {
+	if err != nil {
+		printErr(fmt.Sprintf("failed to render %q output: %v", output.format, err))
+	}
+}
@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	api "github.com/repustate/rcli/api-client/v4"
+)
+
+const (
+	profilesFilename = "profiles.yaml"
+	demoProfile      = "demo"
+
+	// serverEnv overrides the server address of the selected profile
+	serverEnv = "RCLI_SERVER"
+	// profileEnv selects a profile when '--profile' is not given
+	profileEnv = "RCLI_PROFILE"
+)
+
+// profile describes how to connect to a Repustate server.
+type profile struct {
+	Name     string `yaml:"-"`
+	Server   string `yaml:"server"`
+	BasePath string `yaml:"base_path,omitempty"`
+	APIKey   string `yaml:"api_key,omitempty"`
+	Token    string `yaml:"-"`
+	Lang     string `yaml:"lang,omitempty"`
+	// IndexTTL is how long the server keeps an index after its first
+	// document, 0 when indexes do not expire
+	IndexTTL time.Duration `yaml:"index_ttl,omitempty"`
+
+	// AuthSource tells where the credentials were taken from
+	AuthSource string `yaml:"-"`
+}
+
+// profilesConfig is the layout of the profiles file, e.g.:
+//
+//	default: onprem
+//	profiles:
+//	  onprem:
+//	    server: https://repustate.example.com:9000
+//	    base_path: v4
+//	    api_key: secret
+//	    lang: de
+//	    index_ttl: 72h
+type profilesConfig struct {
+	Default  string             `yaml:"default,omitempty"`
+	Profiles map[string]profile `yaml:"profiles"`
+}
+
+func profilesPath() string {
+	return filepath.Join(getConfigDir(), profilesFilename)
+}
+
+func loadProfilesConfig() (profilesConfig, error) {
+	cfg := profilesConfig{}
+
+	data, err := ioutil.ReadFile(profilesPath())
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return cfg, errors.WithMessagef(err, "bad profiles file %s", profilesPath())
+	}
+
+	return cfg, nil
+}
+
+// resolveProfile picks the connection profile to use. Name selection order is
+// the '--profile' flag, RCLI_PROFILE, the config file, then the profiles file
+// default. The built-in "demo" profile points to the public demo server,
+// unless the config file sets another one.
+// RCLI_SERVER, when set, overrides the server address of the chosen profile.
+// Credentials stored with 'rcli auth login', or given in RCLI_API_KEY,
+// take precedence over an API key in the profiles file.
+func resolveProfile(name string, s settings) (profile, error) {
+	cfg, err := loadProfilesConfig()
+	if err != nil {
+		return profile{}, err
+	}
+
+	if name == "" {
+		name = os.Getenv(profileEnv)
+	}
+	if name == "" {
+		name = s.Profile
+	}
+	if name == "" {
+		name = cfg.Default
+	}
+
+	p, ok := cfg.Profiles[name]
+	switch {
+	case ok:
+	case name == "" || name == demoProfile:
+		p = profile{Server: api.DefaultServerURL, BasePath: api.DefaultBasePath}
+		if s.Server != "" {
+			p.Server = s.Server
+		}
+	default:
+		return profile{}, errors.Errorf("unknown profile %q, known profiles: %v", name, profileNames(cfg))
+	}
+
+	if name == "" {
+		name = demoProfile
+	}
+	p.Name = name
+
+	if server := os.Getenv(serverEnv); server != "" {
+		p.Server = server
+	}
+	if p.Lang == "" {
+		p.Lang = s.Lang
+	}
+	if p.IndexTTL == 0 && p.Server == api.DefaultServerURL {
+		p.IndexTTL = demoIndexTTL
+	}
+
+	if p.APIKey != "" {
+		p.AuthSource = profilesPath()
+	}
+	creds, err := loadCredentials()
+	if err != nil {
+		return profile{}, err
+	}
+	if c, ok := creds[name]; ok {
+		p.APIKey, p.Token = c.APIKey, c.Token
+		p.AuthSource = credentialsPath()
+	}
+	if key := os.Getenv(apiKeyEnv); key != "" {
+		p.APIKey, p.Token = key, ""
+		p.AuthSource = apiKeyEnv
+	}
+
+	return p, nil
+}
+
+func profileNames(cfg profilesConfig) []string {
+	names := []string{demoProfile}
+	for name := range cfg.Profiles {
+		if name != demoProfile {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names[1:])
+
+	return names
+}
+
+func (p profile) clientOptions() []api.Option {
+	var opts []api.Option
+	if p.Server != "" {
+		opts = append(opts, api.WithServerURL(p.Server))
+	}
+	if p.BasePath != "" {
+		opts = append(opts, api.WithBasePath(p.BasePath))
+	}
+	if p.APIKey != "" {
+		opts = append(opts, api.WithAPIKey(p.APIKey))
+	}
+	if p.Token != "" {
+		opts = append(opts, api.WithBearerToken(p.Token))
+	}
+	if p.Lang != "" {
+		opts = append(opts, api.WithLanguage(p.Lang))
+	}
+
+	return opts
+}
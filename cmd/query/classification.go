@@ -1,5 +1,11 @@
 package query
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 var (
 	// list of valid classification values to be used for search
 	classes = []string{
@@ -655,6 +661,16 @@ func (c classification) QueryString() string {
 	return string(c) + ":*"
 }
 
+// classValue matches documents mentioning a specific entity of a class.
+type classValue struct {
+	class string
+	value string
+}
+
+func (c classValue) QueryString() string {
+	return c.class + ":" + QuoteValue(c.value)
+}
+
 func HasClass(args ...string) bool {
 	for _, arg := range args {
 		if _, ok := classesLookup[arg]; ok {
@@ -664,3 +680,35 @@ func HasClass(args ...string) bool {
 
 	return false
 }
+
+// SplitClassValue splits a "Class=value" term.
+func SplitClassValue(s string) (class, value string, ok bool) {
+	i := strings.Index(s, "=")
+	if i < 0 {
+		return "", "", false
+	}
+
+	return s[:i], s[i+1:], true
+}
+
+// QuoteValue quotes a classification value unless it is a single plain word.
+func QuoteValue(v string) string {
+	if v != "" && !strings.ContainsAny(v, " \t\n\"()\\:*") {
+		return v
+	}
+
+	return strconv.Quote(v)
+}
+
+func unquote(v string) (string, error) {
+	if !strings.HasPrefix(v, `"`) {
+		return v, nil
+	}
+
+	s, err := strconv.Unquote(v)
+	if err != nil {
+		return "", fmt.Errorf("bad quoted value %s", v)
+	}
+
+	return s, nil
+}
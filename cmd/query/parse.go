@@ -0,0 +1,322 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokLParen
+	tokRParen
+	tokAnd
+	tokOr
+	tokNot
+	tokTerm
+)
+
+const (
+	themePrefix     = "theme:"
+	sentimentPrefix = "sentiment:"
+	classWildcard   = ":*"
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	// col is the 1-based column of the token's first character
+	col int
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of query"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// SyntaxError describes a malformed query expression.
+type SyntaxError struct {
+	// Col is the 1-based column of the offending character.
+	Col int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Col, e.Msg)
+}
+
+// And matches documents matching all of its terms.
+type And []Term
+
+func (a And) QueryString() string {
+	return joinTerms(a, " AND ")
+}
+
+// Or matches documents matching any of its terms.
+type Or []Term
+
+func (o Or) QueryString() string {
+	return joinTerms(o, " OR ")
+}
+
+// Not matches documents not matching its term.
+type Not struct {
+	Term Term
+}
+
+func (n Not) QueryString() string {
+	return "NOT " + groupTerm(n.Term)
+}
+
+func joinTerms(ts []Term, op string) string {
+	parts := make([]string, len(ts))
+	for i, t := range ts {
+		parts[i] = groupTerm(t)
+	}
+
+	return strings.Join(parts, op)
+}
+
+// groupTerm wraps compound terms in parentheses.
+func groupTerm(t Term) string {
+	switch t.(type) {
+	case And, Or:
+		return "(" + t.QueryString() + ")"
+	}
+
+	return t.QueryString()
+}
+
+// Parse parses a boolean query expression, e.g.
+// "(Person.politician OR Org.business) AND NOT neg AND theme:finance".
+// Terms next to each other without an operator are joined with AND,
+// AND binds tighter than OR. Every term is validated against the known
+// themes, sentiments and classifications.
+func Parse(expr string) (Term, error) {
+	p := &parser{}
+	if err := p.lex(expr); err != nil {
+		return nil, err
+	}
+
+	t, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, p.unexpected(tok)
+	}
+
+	return t, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) lex(expr string) error {
+	runes := []rune(expr)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			p.tokens = append(p.tokens, token{kind: tokLParen, text: "(", col: i + 1})
+			i++
+		case r == ')':
+			p.tokens = append(p.tokens, token{kind: tokRParen, text: ")", col: i + 1})
+			i++
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '(' && runes[i] != ')' {
+				// quoted values may hold spaces and parentheses
+				if runes[i] == '"' {
+					quote := i
+					for i++; i < len(runes) && runes[i] != '"'; i++ {
+						if runes[i] == '\\' {
+							i++
+						}
+					}
+					if i >= len(runes) {
+						return &SyntaxError{Col: quote + 1, Msg: "unterminated quoted value"}
+					}
+				}
+				i++
+			}
+			text := string(runes[start:i])
+			p.tokens = append(p.tokens, token{kind: keyword(text), text: text, col: start + 1})
+		}
+	}
+	p.tokens = append(p.tokens, token{kind: tokEOF, col: len(runes) + 1})
+
+	return nil
+}
+
+func keyword(s string) tokenKind {
+	switch strings.ToUpper(s) {
+	case "AND":
+		return tokAnd
+	case "OR":
+		return tokOr
+	case "NOT":
+		return tokNot
+	}
+
+	return tokTerm
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+
+	return t
+}
+
+func (p *parser) unexpected(t token) error {
+	return &SyntaxError{Col: t.col, Msg: fmt.Sprintf("unexpected %s", t)}
+}
+
+// parseOr parses: and ("OR" and)*
+func (p *parser) parseOr() (Term, error) {
+	t, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	terms := Or{t}
+	for p.peek().kind == tokOr {
+		p.next()
+		t, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, t)
+	}
+	if len(terms) == 1 {
+		return terms[0], nil
+	}
+
+	return terms, nil
+}
+
+// parseAnd parses: not (["AND"] not)*
+func (p *parser) parseAnd() (Term, error) {
+	t, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	terms := And{t}
+	for {
+		switch p.peek().kind {
+		case tokAnd:
+			p.next()
+		case tokNot, tokLParen, tokTerm:
+		default:
+			if len(terms) == 1 {
+				return terms[0], nil
+			}
+			return terms, nil
+		}
+
+		t, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, t)
+	}
+}
+
+// parseNot parses: "NOT" not | primary
+func (p *parser) parseNot() (Term, error) {
+	if p.peek().kind != tokNot {
+		return p.parsePrimary()
+	}
+
+	p.next()
+	t, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	return Not{Term: t}, nil
+}
+
+// parsePrimary parses: "(" or ")" | term
+func (p *parser) parsePrimary() (Term, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokLParen:
+		t, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			if closing.kind == tokEOF {
+				return nil, &SyntaxError{Col: tok.col, Msg: "unclosed parenthesis"}
+			}
+			return nil, p.unexpected(closing)
+		}
+		return t, nil
+	case tokTerm:
+		return parseTerm(tok)
+	}
+
+	return nil, p.unexpected(tok)
+}
+
+// parseTerm validates a single query term, either in its short form
+// ("finance", "neg", "Location.city") or prefixed the way the search
+// engine expects it ("theme:finance", "sentiment:neg", "Location.city:*").
+// Classifications may be restricted to a value, e.g. "Location.city=Paris"
+// or `Person.politician="Angela Merkel"`.
+func parseTerm(tok token) (Term, error) {
+	s := tok.text
+	if class, value, ok := SplitClassValue(s); ok {
+		if !HasClass(class) {
+			return nil, &SyntaxError{Col: tok.col, Msg: fmt.Sprintf("unknown classification %q", class)}
+		}
+		v, err := unquote(value)
+		if err != nil {
+			return nil, &SyntaxError{Col: tok.col + len([]rune(class)) + 1, Msg: err.Error()}
+		}
+		if v == "" {
+			return nil, &SyntaxError{Col: tok.col, Msg: fmt.Sprintf("empty value for %q", class)}
+		}
+		return classValue{class: class, value: v}, nil
+	}
+
+	switch {
+	case strings.HasPrefix(s, themePrefix):
+		if v := strings.TrimPrefix(s, themePrefix); HasTheme(v) {
+			return theme(v), nil
+		}
+	case strings.HasPrefix(s, sentimentPrefix):
+		if v := strings.TrimPrefix(s, sentimentPrefix); HasSentiment(v) {
+			return sentiment(v), nil
+		}
+	case strings.HasSuffix(s, classWildcard):
+		if v := strings.TrimSuffix(s, classWildcard); HasClass(v) {
+			return classification(v), nil
+		}
+	case HasTheme(s):
+		return theme(s), nil
+	case HasSentiment(s):
+		return sentiment(s), nil
+	case HasClass(s):
+		return classification(s), nil
+	}
+
+	return nil, &SyntaxError{Col: tok.col, Msg: fmt.Sprintf("unknown query term %q", s)}
+}
@@ -1,32 +1,31 @@
 package query
 
 import (
-	"fmt"
 	"strings"
 )
 
+var (
+	operators = []string{
+		"AND",
+		"OR",
+		"NOT",
+	}
+)
+
 type Term interface {
 	QueryString() string
 }
 
+// Build turns command line arguments into a search engine query.
+// Arguments are joined with spaces and parsed as a single expression,
+// see Parse.
 func Build(args []string) (string, error) {
-	terms := make([]string, len(args))
-	for i := range args {
-		var t Term
-		if HasTheme(args[i]) {
-			t = theme(args[i])
-		} else if HasSentiment(args[i]) {
-			t = sentiment(args[i])
-		} else if HasClass(args[i]) {
-			t = classification(args[i])
-		} else {
-			return "", fmt.Errorf("unknown query term: %q", args[i])
-		}
-
-		terms[i] = t.QueryString()
+	t, err := Parse(JoinArgs(args))
+	if err != nil {
+		return "", err
 	}
 
-	return strings.Join(terms, " AND "), nil
+	return t.QueryString(), nil
 }
 
 func ListTerms(addThemes, addSents, addClasses bool, prefix string) []string {
@@ -45,6 +44,39 @@ func ListTerms(addThemes, addSents, addClasses bool, prefix string) []string {
 	return res
 }
 
+// JoinArgs joins command line arguments into a query expression. Class
+// values the shell already unquoted, e.g. `Person.politician=Angela Merkel`,
+// are quoted again so they stay a single term.
+func JoinArgs(args []string) string {
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		parts[i] = arg
+		class, value, ok := SplitClassValue(arg)
+		if ok && !strings.HasPrefix(value, `"`) && strings.ContainsAny(value, " \t()") {
+			parts[i] = class + "=" + QuoteValue(value)
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// ListOperators lists boolean query operators starting with prefix.
+func ListOperators(prefix string) []string {
+	return filter(operators, prefix)
+}
+
+// HasOperator reports whether any of args is a boolean operator
+// or contains a parenthesis.
+func HasOperator(args ...string) bool {
+	for _, arg := range args {
+		if keyword(arg) != tokTerm || strings.ContainsAny(arg, "()") {
+			return true
+		}
+	}
+
+	return false
+}
+
 func filter(ts []string, prefix string) []string {
 	if prefix == "" {
 		return ts
@@ -0,0 +1,106 @@
+package query
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"finance", "theme:finance"},
+		{"theme:finance", "theme:finance"},
+		{"neg", "sentiment:neg"},
+		{"Location.city", "Location.city:*"},
+		{"Location.city:*", "Location.city:*"},
+		{"finance neg", "theme:finance AND sentiment:neg"},
+		{"pos and neg", "sentiment:pos AND sentiment:neg"},
+		{"finance or neg", "theme:finance OR sentiment:neg"},
+		{"finance OR neg Location.city", "theme:finance OR (sentiment:neg AND Location.city:*)"},
+		{"(Person.politician OR Org.business) AND NOT neg AND theme:finance", "(Person.politician:* OR Org.business:*) AND NOT sentiment:neg AND theme:finance"},
+		{"NOT NOT neg", "NOT NOT sentiment:neg"},
+		{"Location.city=Paris", "Location.city:Paris"},
+		{`Person.politician="Angela Merkel"`, `Person.politician:"Angela Merkel"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			term, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := term.QueryString(); got != tt.want {
+				t.Errorf("Parse() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		col  int
+		msg  string
+	}{
+		{"", 1, "unexpected end of query"},
+		{"(finance", 1, "unclosed parenthesis"},
+		{"finance)", 8, `unexpected ")"`},
+		{"AND finance", 1, `unexpected "AND"`},
+		{"finance OR", 11, "unexpected end of query"},
+		{"bogus", 1, `unknown query term "bogus"`},
+		{"finance Foo.bar", 9, `unknown query term "Foo.bar"`},
+		{"Location.city=", 1, `empty value for "Location.city"`},
+		{`Location.city="Paris`, 15, "unterminated quoted value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Parse(tt.expr)
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("Parse() error = %v, want a SyntaxError", err)
+			}
+			if syntaxErr.Col != tt.col || syntaxErr.Msg != tt.msg {
+				t.Errorf("Parse() error = column %d: %s, want column %d: %s", syntaxErr.Col, syntaxErr.Msg, tt.col, tt.msg)
+			}
+		})
+	}
+}
+
+func TestBuild(t *testing.T) {
+	// the shell unquotes values with spaces, which are quoted again
+	args := []string{"Person.politician=Angela Merkel", "OR", "Location.city=New York", "neg"}
+
+	joined := JoinArgs(args)
+	if want := `Person.politician="Angela Merkel" OR Location.city="New York" neg`; joined != want {
+		t.Errorf("JoinArgs() = %q, want %q", joined, want)
+	}
+
+	q, err := Build(args)
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if want := `Person.politician:"Angela Merkel" OR (Location.city:"New York" AND sentiment:neg)`; q != want {
+		t.Errorf("Build() = %q, want %q", q, want)
+	}
+}
+
+func TestHasOperator(t *testing.T) {
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"finance", "neg"}, false},
+		{[]string{"finance", "OR", "neg"}, true},
+		{[]string{"(finance"}, true},
+		{[]string{"not", "neg"}, true},
+	}
+
+	for _, tt := range tests {
+		if got := HasOperator(tt.args...); got != tt.want {
+			t.Errorf("HasOperator(%q) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
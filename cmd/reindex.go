@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	api "github.com/repustate/rcli/api-client/v4"
+)
+
+const fromFlag = "from"
+
+// newReindexCmd represents the command replaying a mirror, active is the
+// name of the identity selected for this run.
+func newReindexCmd(c api.Indexer, user identity, active *string, s *settings) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "reindex",
+		Short: "Index the documents of the local mirror again",
+		Long: `Index the documents kept in the local mirror again, e.g. once the demo index expired.
+
+With the mirror enabled ('rcli config set mirror true') every indexed, updated
+or deleted document is recorded locally per identity. 'rcli reindex' sends the
+mirrored documents of the '--from' identity to the identity in use:
+rcli identity new fresh --use
+rcli reindex --from default
+
+Disabling the mirror keeps the documents recorded so far, which can still be
+reindexed; the reindexed documents are only recorded again while the mirror is
+enabled.
+
+Like bulk index runs, reindex runs are recorded as jobs which can be continued
+with '--resume <job>'.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			from := cmd.Flag(fromFlag).Value.String()
+			concurrency, _ := cmd.Flags().GetInt(concurrencyFlag)
+			resume := cmd.Flag(resumeFlag).Value.String()
+
+			var j *job
+			if resume != "" {
+				var err error
+				if j, err = loadJob(resume); err != nil {
+					printErr(fmt.Sprintf("failed to resume job: %v", err))
+					return
+				}
+				defer j.close()
+				if j.Mirror == "" {
+					printErr(fmt.Sprintf("job %s indexes files, resume it with `rcli index --resume %s`", j.ID, j.ID))
+					return
+				}
+				from = j.Mirror
+			}
+			if from == "" {
+				from = *active
+			}
+
+			ids, err := loadIdentities()
+			if err != nil {
+				printErr(err.Error())
+				return
+			}
+			fromUser, ok := ids.Identities[from]
+			if !ok {
+				printErr(fmt.Sprintf("unknown identity %q, known identities: %v", from, ids.names()))
+				return
+			}
+
+			n, err := countMirror(fromUser)
+			if err != nil {
+				printErr(err.Error())
+				return
+			}
+			if n == 0 && !s.Mirror {
+				printErr(fmt.Sprintf("the mirror of identity %q is empty, the local mirror is disabled, enable it with `rcli config set mirror true`", from))
+				return
+			}
+			if n == 0 {
+				printErr(fmt.Sprintf("the mirror of identity %q is empty", from))
+				return
+			}
+
+			if j == nil {
+				if j, err = newJob(&job{Mirror: from}); err != nil {
+					printErr(fmt.Sprintf("failed to create job manifest: %v", err))
+					return
+				}
+				defer j.close()
+			}
+			printMsg(fmt.Sprintf("Reindexing %d documents of identity %q into %q as job %s.", n, from, *active, j.ID))
+
+			summary := indexBulk(cmd.Context(), c, j, streamMirror(cmd.Context(), fromUser), n, "", user(), concurrency)
+
+			// the copies replaced in the mirror of the identity in use
+			// expired along with its index, the new ones are only mirrored
+			// while the mirror is enabled
+			if fromUser == user() && s.Mirror {
+				var stale []mirrorRecord
+				for _, r := range summary.Results {
+					if r.Res != nil && r.Res.ID != "" && r.Res.ID != r.Doc.Replaces {
+						stale = append(stale, mirrorRecord{ID: r.Doc.Replaces, Time: time.Now(), Deleted: true})
+					}
+				}
+				if err := putMirror(fromUser, stale...); err != nil {
+					printErr(fmt.Sprintf("Failed to update the local mirror: %v", err))
+				}
+			}
+
+			printBulkSummary(summary, j)
+		},
+		Example: "reindex\r\nreindex --from default\r\nreindex --resume 20201214-093000",
+	}
+
+	cmd.Flags().String(fromFlag, "", "Identity whose mirror to replay (default is the identity in use)")
+	cmd.RegisterFlagCompletionFunc(fromFlag, completeIdentityFlag)
+	cmd.Flags().Int(concurrencyFlag, 4, "Number of documents indexed in parallel")
+	cmd.Flags().String(resumeFlag, "", "Resume an interrupted reindex job, skipping documents it already indexed")
+
+	return cmd
+}
+
+// streamMirror reads the documents in the mirror of user as bulk documents.
+func streamMirror(ctx context.Context, user string) <-chan bulkDoc {
+	ch := make(chan bulkDoc)
+	send := func(doc bulkDoc) bool {
+		select {
+		case ch <- doc:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	go func() {
+		defer close(ch)
+		err := walkMirror(user, func(r mirrorRecord) bool {
+			doc := bulkDoc{Path: r.Source, Text: r.Text, Lang: r.Lang, Replaces: r.key(), Metadata: r.Metadata}
+			if doc.Path == "" {
+				doc.Path = "mirror:" + r.key()
+			}
+			if r.OwnID {
+				doc.ID = r.ID
+			}
+			return send(doc)
+		})
+		if err != nil {
+			send(bulkDoc{Path: "mirror", Err: err})
+		}
+	}()
+
+	return ch
+}
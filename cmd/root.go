@@ -1,29 +1,83 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"strings"
+	"time"
 
-	"github.com/google/uuid"
 	"github.com/spf13/cobra"
 
 	client "github.com/repustate/rcli/api-client/v4"
 )
 
+const (
+	profileFlag  = "profile"
+	identityFlag = "identity"
+	timeoutFlag  = "timeout"
+	retriesFlag  = "retries"
+)
+
 // rootCmd represents the base command when called without any subcommands
 var (
 	userUuid = ""
 
+	// activeIdentity is the name of the identity userUuid belongs to
+	activeIdentity string
+
+	// svc is configured from the selected connection profile
+	// before any subcommand runs
+	svc = &backend{}
+
+	// activeSettings are the preferences of the config file
+	activeSettings settings
+
+	// activeProfile is the connection profile selected for this run
+	activeProfile profile
+
+	// currentUser is the identity commands work on
+	currentUser identity = func() string { return userUuid }
+
 	rootCmd = &cobra.Command{
 		Use:   "rcli",
 		Short: "Repustate CLI for Semantic Search",
 		Long:  `Command-line interface to Repustate's Semantic Search engine`,
-		// populates user uuid every time executed
+		// populates user uuid and api client every time executed
 		PersistentPreRun: func(cmd *cobra.Command, args []string) {
-			userUuid = loadUserUUID()
-			if userUuid == "" {
-				userUuid = uuid.New().String()
-				storeUserUUID(userUuid)
+			s, err := loadSettings()
+			if err != nil {
+				printErr(err.Error())
+				os.Exit(1)
+			}
+			s.applyColor()
+			activeSettings = s
+
+			activeIdentity, userUuid, err = resolveIdentity(cmd.Flag(identityFlag).Value.String(), s)
+			if err != nil {
+				printErr(err.Error())
+				os.Exit(1)
+			}
+
+			format := cmd.Flag(outputFlag).Value.String()
+			if !cmd.Flags().Changed(outputFlag) && s.Output != "" {
+				format = s.Output
+			}
+			if output, err = newOutputOptions(format, cmd.Flag(templateFlag).Value.String()); err != nil {
+				printErr(err.Error())
+				os.Exit(1)
+			}
+
+			activeProfile, err = resolveProfile(cmd.Flag(profileFlag).Value.String(), s)
+			if err != nil {
+				printErr(err.Error())
+				os.Exit(1)
+			}
+			svc.Service, err = newService(cmd, activeProfile, s)
+			if err != nil {
+				printErr(err.Error())
+				os.Exit(1)
 			}
 		},
 	}
@@ -32,22 +86,79 @@ var (
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	api, err := client.New()
-	if err != nil {
-		printErr(err.Error())
-		os.Exit(1)
-	}
+	rootCmd.PersistentFlags().String(profileFlag, "",
+		fmt.Sprintf("Connection profile from %s (default is the public demo server)", profilesPath()))
+	rootCmd.PersistentFlags().String(identityFlag, "",
+		fmt.Sprintf("Identity whose index to use, from %s (default is the one set with 'rcli identity use')", identitiesPath()))
+	rootCmd.RegisterFlagCompletionFunc(identityFlag, completeIdentityFlag)
+	rootCmd.PersistentFlags().StringP(outputFlag, "o", outputText,
+		fmt.Sprintf("Output format, one of: %s", strings.Join(outputFormats, ", ")))
+	rootCmd.PersistentFlags().String(templateFlag, "",
+		"Go text/template rendering the result with '--output=template', e.g. '{{.Total}}'")
+	rootCmd.PersistentFlags().Duration(timeoutFlag, 30*time.Second, "Timeout of a single request to the server, 0 disables it")
+	rootCmd.PersistentFlags().Int(retriesFlag, client.DefaultRetryPolicy.MaxRetries,
+		"Number of retries for requests failing with a connection error, 429 or 5xx status")
+	rootCmd.PersistentFlags().Float64(rateFlag, 0, "Maximum number of requests per second, 0 means no limit")
+	rootCmd.PersistentFlags().Bool(debugFlag, false, "Log every request to the server on stderr, credentials redacted")
 
 	// install user-defined commands
 	for _, c := range []*cobra.Command{
-		newIndexCmd(&api),
-		newSearchCmd(&api),
+		newIndexCmd(svc, currentUser, &activeProfile),
+		newSearchCmd(svc, currentUser, &activeProfile),
+		newDocCmd(svc, currentUser, &activeProfile),
+		newAuthCmd(&activeProfile),
+		newIdentityCmd(&activeIdentity),
+		newConfigCmd(),
+		newReindexCmd(svc, currentUser, &activeIdentity, &activeSettings),
+		newStatusCmd(currentUser, &activeIdentity, &activeProfile, &activeSettings),
+		newDevServerCmd(),
+		newCompletionCmd(),
 	} {
 		rootCmd.AddCommand(c)
 	}
 
-	if err := rootCmd.Execute(); err != nil {
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
 }
+
+// clientNetOptions builds client timeout and retry options from global flags.
+func clientNetOptions(cmd *cobra.Command) []client.Option {
+	flags := cmd.Flags()
+	timeout, _ := flags.GetDuration(timeoutFlag)
+	retries, _ := flags.GetInt(retriesFlag)
+
+	policy := client.DefaultRetryPolicy
+	policy.MaxRetries = retries
+
+	return []client.Option{
+		client.WithTimeout(timeout),
+		client.WithRetryPolicy(policy),
+	}
+}
+
+// interruptContext returns a context cancelled on the first Ctrl-C,
+// a second one terminates the process right away.
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	go func() {
+		select {
+		case <-sig:
+			cancel()
+		case <-ctx.Done():
+			signal.Stop(sig)
+			return
+		}
+		<-sig
+		os.Exit(130)
+	}()
+
+	return ctx, cancel
+}
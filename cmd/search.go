@@ -1,9 +1,12 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"strings"
 
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
 	api "github.com/repustate/rcli/api-client/v4"
@@ -12,15 +15,25 @@ import (
 
 const (
 	listTerms = "list-terms"
+	limitFlag = "limit"
+	pageFlag  = "page"
+	allFlag   = "all"
 )
 
 // registerCmd represents the search command
-func newSearchCmd(c *api.Client) *cobra.Command {
+func newSearchCmd(c api.Searcher, user identity, p *profile) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "search",
 		Short: "Semantically searches index using the provided query",
 		Long: `Run multilingual semantic search across indexed documents using query provided. 
 
+Query terms can be combined with AND, OR, NOT and parentheses, terms next to
+each other are joined with AND:
+rcli search "(Person.politician OR Org.business) AND NOT neg AND theme:finance"
+
+A classification can be narrowed down to a specific entity:
+rcli search Location.city=Paris 'Person.politician="Angela Merkel"'
+
 To list all available query terms use '--list-terms'`,
 		Run: func(cmd *cobra.Command, args []string) {
 			printTerms := cmd.Flag(listTerms).Value.String()
@@ -37,66 +50,219 @@ To list all available query terms use '--list-terms'`,
 				return
 			}
 
-			if len(args) > 3 {
-				msg := fmt.Sprintf("maximum 3 search query terms allowed, got %d", len(args))
-				printErr(msg)
+			limit, _ := cmd.Flags().GetInt(limitFlag)
+			pageNum, _ := cmd.Flags().GetInt(pageFlag)
+			all, _ := cmd.Flags().GetBool(allFlag)
+			if limit < 0 || pageNum < 1 {
+				printErr("'--limit' must not be negative and '--page' must be at least 1")
+				cmd.Usage()
+				return
+			}
+			if pageNum > 1 && (limit == 0 || all) {
+				printErr("'--page' requires '--limit' and cannot be used with '--all'")
 				cmd.Usage()
 				return
 			}
 
 			q, err := query.Build(args)
 			if err != nil {
-				printErr(err.Error())
-				cmd.Usage()
+				printQueryErr(query.JoinArgs(args), err)
 				return
 			}
-			res, err := c.Search(q, userUuid)
 
-			printSearchResult(res, err)
+			page := api.Page{Limit: limit, Offset: (pageNum - 1) * limit}
+			var res *api.SearchResult
+			if all {
+				page = api.Page{}
+				res, err = searchAll(cmd.Context(), c, q, user(), limit)
+			} else {
+				res, err = c.SearchPage(cmd.Context(), q, user(), page)
+			}
+			if err == nil {
+				var entities []api.Entity
+				for _, doc := range res.Documents {
+					entities = append(entities, doc.Entities...)
+				}
+				rememberEntities(entities)
+			}
+
+			printSearchResult(res, err, page)
+			if err == nil {
+				warnExpiry(*p, user())
+			}
 		},
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 			var completions []string
 
-			themes := query.HasTheme(args...)
-			sents := query.HasSentiment(args...)
-			classes := query.HasClass(args...)
+			// values of a classification, e.g. "Location.city=Par"
+			if strings.Contains(toComplete, "=") {
+				completions, _ = completeClassValue(toComplete)
+				return completions, cobra.ShellCompDirectiveNoFileComp
+			}
 
-			// every query term type is used,
-			// no completion options available
-			if classes && sents && themes {
+			// boolean expressions may use every term type any number of times
+			if query.HasOperator(args...) {
+				completions = query.ListTerms(true, true, true, toComplete)
+				completions = append(completions, query.ListOperators(toComplete)...)
 				return completions, cobra.ShellCompDirectiveNoFileComp
 			}
 
+			themes := query.HasTheme(args...)
+			sents := query.HasSentiment(args...)
+			classes := query.HasClass(args...)
+
 			// list terms for given prefix in available term types only
 			completions = query.ListTerms(!themes, !sents, !classes, toComplete)
+			if values, ok := completeClassValue(toComplete); ok {
+				completions = append(completions, values...)
+			}
+			if len(args) != 0 {
+				completions = append(completions, query.ListOperators(toComplete)...)
+			}
 			return completions, cobra.ShellCompDirectiveNoFileComp
 		},
 
-		Example: "search Location.city\r\nsearch pos sports Location.city\r\nsearch --list-terms",
+		Example: "search Location.city\r\nsearch pos sports Location.city\r\nsearch --limit 10 --page 2 pos\r\n" +
+			"search \"Location.city OR Location.country\" NOT neg\r\nsearch --list-terms",
 	}
 
 	cmd.Flags().Bool(listTerms, false, "Lists available query terms")
+	cmd.Flags().Int(limitFlag, 0, "Maximum number of results to show (default is up to the server)")
+	cmd.Flags().Int(pageFlag, 1, "Page of '--limit' results to show")
+	cmd.Flags().Bool(allFlag, false, "Fetch all results, '--limit' at a time")
 
 	return cmd
 }
 
-func printSearchResult(res *api.SearchResult, err error) {
+// searchAll fetches every match of query, pageSize matches per request.
+func searchAll(ctx context.Context, c api.Searcher, q, user string, pageSize int) (*api.SearchResult, error) {
+	it := api.NewSearchIterator(c, q, user, pageSize)
+	res := &api.SearchResult{}
+	for it.Next(ctx) {
+		res.Documents = append(res.Documents, it.Document())
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	res.Total = it.Total()
+
+	return res, nil
+}
+
+// printSearchResult prints matches of the given page of results.
+func printSearchResult(res *api.SearchResult, err error, page api.Page) {
 	if err != nil {
 		msg := fmt.Sprintf("Search failed: %v", err)
 		printErr(msg)
+		printErrHint(err, "Check the query terms, run `rcli search --list-terms` to see the valid ones.")
+	} else if output.format != outputText {
+		printRenderErr(renderSearchResult(os.Stdout, output, res))
 	} else {
 		if res.Total == 0 {
 			fmt.Println("No documents found.")
 			return
 		}
 		fmt.Printf("Found %d results:\n", res.Total)
-		for _, doc := range res.Documents {
+		partial := len(res.Documents) < res.Total
+		if partial && len(res.Documents) != 0 {
+			fmt.Printf("Showing results %d-%d:\n", page.Offset+1, page.Offset+len(res.Documents))
+		}
+		for _, group := range groupPassages(res.Documents) {
 			fmt.Println("--------------------------------------------------------------------------------")
-			fmt.Printf("%s\n\nEntities:\n", doc.Text)
-			for _, entity := range doc.Entities {
-				classes := strings.Join(entity.Classifications, ", ")
-				fmt.Printf("\t%q (%s)\n", entity.Title, classes)
+			first := group[0]
+			if first.Metadata[metaParent] == "" {
+				fmt.Printf("ID: %s\n", first.ID)
+				printMatch(first, first.Metadata)
+				continue
 			}
+
+			fmt.Printf("Document %s, %d of %s passages match\n", first.Metadata[metaSource], len(group), first.Metadata[metaChunks])
+			for i, doc := range group {
+				if i > 0 {
+					fmt.Println()
+				}
+				// subtitles are located by time rather than position
+				if start := doc.Metadata[metaStart]; start != "" {
+					fmt.Printf("%s @ %s, ID: %s\n", doc.Metadata[metaSource], start, doc.ID)
+				} else {
+					fmt.Printf("Passage %s/%s, ID: %s\n", doc.Metadata[metaChunk], doc.Metadata[metaChunks], doc.ID)
+				}
+				printMatch(doc, documentMetadata(doc.Metadata))
+			}
+		}
+
+		if partial && page.Offset+len(res.Documents) < res.Total {
+			fmt.Println("--------------------------------------------------------------------------------")
+			if page.Limit > 0 {
+				printMsg(fmt.Sprintf("Use '--page %d' to see more results, or '--all' to see all of them.", page.Offset/page.Limit+2))
+			} else {
+				printMsg("Use '--limit' and '--page' to see more results, or '--all' to see all of them.")
+			}
+		}
+	}
+}
+
+// printMatch prints the text, entities and metadata md of a matching
+// document.
+func printMatch(doc api.Document, md map[string]string) {
+	fmt.Printf("%s\n\nEntities:\n", doc.Text)
+	for _, entity := range doc.Entities {
+		classes := strings.Join(entity.Classifications, ", ")
+		fmt.Printf("\t%q (%s)\n", entity.Title, classes)
+	}
+	printMetadata(md)
+}
+
+// groupPassages groups the matching passages of a split document, in the
+// order of the best match of each document.
+func groupPassages(docs []api.Document) [][]api.Document {
+	var groups [][]api.Document
+	parents := map[string]int{}
+	for _, doc := range docs {
+		parent := doc.Metadata[metaParent]
+		if i, ok := parents[parent]; ok && parent != "" {
+			groups[i] = append(groups[i], doc)
+			continue
 		}
+		parents[parent] = len(groups)
+		groups = append(groups, []api.Document{doc})
+	}
+
+	return groups
+}
+
+// documentMetadata returns the metadata of the document a passage was
+// split from, without the fields describing the passage.
+func documentMetadata(md map[string]string) map[string]string {
+	doc := map[string]string{}
+	for k, v := range md {
+		switch k {
+		case metaParent, metaChunk, metaChunks, metaSource, metaStart, metaEnd:
+		default:
+			doc[k] = v
+		}
+	}
+
+	return doc
+}
+
+// printMetadata lists the metadata fields of a document, if any.
+func printMetadata(md map[string]string) {
+	if len(md) == 0 {
+		return
+	}
+	fmt.Println("Metadata:")
+	for _, k := range sortedKeys(md) {
+		fmt.Printf("\t%s: %s\n", k, md[k])
+	}
+}
+
+// printQueryErr reports a malformed query, pointing at the offending column.
+func printQueryErr(q string, err error) {
+	printErr(fmt.Sprintf("bad search query: %v", err))
+
+	var syntaxErr *query.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		fmt.Fprintf(os.Stderr, "  %s\n  %s^\n", q, strings.Repeat(" ", syntaxErr.Col-1))
 	}
 }
@@ -0,0 +1,250 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	configFilename = "config.yaml"
+
+	// settingsVersion is the layout version of the config file, bumped on
+	// any change needing a migration of existing files
+	settingsVersion = 1
+
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+// settings are user preferences kept in the config file, e.g.:
+//
+//	version: 1
+//	identity: project-x
+//	profile: onprem
+//	lang: de
+//	output: table
+//	color: never
+//	mirror: true
+//
+// Command-line flags and environment variables take precedence over them.
+type settings struct {
+	Version  int    `yaml:"version"`
+	Identity string `yaml:"identity,omitempty"`
+	Profile  string `yaml:"profile,omitempty"`
+	Server   string `yaml:"server,omitempty"`
+	Lang     string `yaml:"lang,omitempty"`
+	Output   string `yaml:"output,omitempty"`
+	Color    string `yaml:"color,omitempty"`
+	Mirror   bool   `yaml:"mirror,omitempty"`
+}
+
+// setting describes a key of the config file.
+type setting struct {
+	usage string
+	get   func(s *settings) string
+	// set validates and stores the value, an empty value resets the key
+	set func(s *settings, value string) error
+}
+
+var settingKeys = map[string]setting{
+	"identity": {
+		usage: "Identity in use, see 'rcli identity'",
+		get:   func(s *settings) string { return s.Identity },
+		set: func(s *settings, value string) error {
+			if value != "" {
+				ids, err := loadIdentities()
+				if err != nil {
+					return err
+				}
+				if _, ok := ids.Identities[value]; !ok {
+					return errors.Errorf("unknown identity %q, known identities: %v", value, ids.names())
+				}
+			}
+			s.Identity = value
+			return nil
+		},
+	},
+	"profile": {
+		usage: "Connection profile used without '--profile'",
+		get:   func(s *settings) string { return s.Profile },
+		set: func(s *settings, value string) error {
+			if value != "" && value != demoProfile {
+				cfg, err := loadProfilesConfig()
+				if err != nil {
+					return err
+				}
+				if _, ok := cfg.Profiles[value]; !ok {
+					return errors.Errorf("unknown profile %q, known profiles: %v", value, profileNames(cfg))
+				}
+			}
+			s.Profile = value
+			return nil
+		},
+	},
+	"server": {
+		usage: "Server address of the built-in demo profile",
+		get:   func(s *settings) string { return s.Server },
+		set: func(s *settings, value string) error {
+			if value != "" {
+				u, err := url.Parse(value)
+				if err != nil || u.Scheme == "" || u.Host == "" {
+					return errors.Errorf("bad server address %q, expected e.g. http://127.0.0.1:9000", value)
+				}
+			}
+			s.Server = value
+			return nil
+		},
+	},
+	"lang": {
+		usage: "Language of indexed content without '--lang'",
+		get:   func(s *settings) string { return s.Lang },
+		set: func(s *settings, value string) error {
+			if value != "" {
+				if err := checkLang(value); err != nil {
+					return err
+				}
+			}
+			s.Lang = value
+			return nil
+		},
+	},
+	"output": {
+		usage: "Output format without '--output'",
+		get:   func(s *settings) string { return s.Output },
+		set: func(s *settings, value string) error {
+			if value == outputTemplate {
+				return errors.Errorf("%q needs '--%s', it cannot be the default format", outputTemplate, templateFlag)
+			}
+			if _, err := newOutputOptions(value, ""); value != "" && err != nil {
+				return err
+			}
+			s.Output = value
+			return nil
+		},
+	},
+	"mirror": {
+		usage: "Keep a local copy of indexed documents for 'rcli reindex', true or false",
+		get:   func(s *settings) string { return strconv.FormatBool(s.Mirror) },
+		set: func(s *settings, value string) error {
+			if value == "" {
+				s.Mirror = false
+				return nil
+			}
+			on, err := strconv.ParseBool(value)
+			if err != nil {
+				return errors.Errorf("bad mirror setting %q, use true or false", value)
+			}
+			s.Mirror = on
+			return nil
+		},
+	},
+	"color": {
+		usage: "Colored messages, one of: auto, always, never",
+		get:   func(s *settings) string { return s.Color },
+		set: func(s *settings, value string) error {
+			switch value {
+			case "", colorAuto, colorAlways, colorNever:
+			default:
+				return errors.Errorf("bad color setting %q, use one of: auto, always, never", value)
+			}
+			s.Color = value
+			return nil
+		},
+	},
+}
+
+// settingNames lists config file keys alphabetically.
+func settingNames() []string {
+	names := make([]string, 0, len(settingKeys))
+	for name := range settingKeys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func configPath() string {
+	return filepath.Join(getConfigDir(), configFilename)
+}
+
+// loadSettings reads the config file, migrating it to the current layout
+// first when it was written by an older rcli or does not exist yet.
+func loadSettings() (settings, error) {
+	s := settings{}
+
+	data, err := ioutil.ReadFile(configPath())
+	if err != nil && !os.IsNotExist(err) {
+		return s, err
+	}
+	if err == nil {
+		if err := yaml.Unmarshal(data, &s); err != nil {
+			return s, errors.WithMessagef(err, "bad config file %s", configPath())
+		}
+	}
+
+	if s.Version > settingsVersion {
+		return s, errors.Errorf("config file %s has version %d, this rcli supports up to %d, please upgrade",
+			configPath(), s.Version, settingsVersion)
+	}
+	if s.Version < settingsVersion {
+		if err := migrateSettings(&s); err != nil {
+			return s, errors.WithMessagef(err, "failed to migrate config file %s", configPath())
+		}
+		if err := storeSettings(s); err != nil {
+			return s, errors.WithMessage(err, "failed to store config")
+		}
+	}
+
+	return s, nil
+}
+
+func storeSettings(s settings) error {
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(configPath(), data)
+}
+
+// migrateSettings upgrades s one version at a time.
+func migrateSettings(s *settings) error {
+	if s.Version == 0 {
+		// the identity in use was kept in the identities file, which
+		// itself imports the UUID of the legacy ~/.repustate file
+		ids, err := loadIdentities()
+		if err != nil {
+			return err
+		}
+		if ids.Current != "" {
+			s.Identity = ids.Current
+			ids.Current = ""
+			if err := storeIdentities(ids); err != nil {
+				return err
+			}
+		}
+		s.Version = 1
+	}
+
+	return nil
+}
+
+// applyColor enables or disables colored messages.
+func (s settings) applyColor() {
+	switch s.Color {
+	case colorAlways:
+		color.NoColor = false
+	case colorNever:
+		color.NoColor = true
+	}
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// newStatusCmd represents the command describing the index in use
+func newStatusCmd(user identity, activeIdentity *string, p *profile, s *settings) *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show the identity and server in use and when the index expires",
+		Long: `Show the identity and server in use, when the index got its first document
+and how long until the server deletes it, and the size of the local mirror.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Printf("Identity: %s (%s)\n", *activeIdentity, user())
+			fmt.Printf("Profile:  %s (%s)\n", p.Name, p.Server)
+
+			e, err := loadIndexExpiry(user(), p.IndexTTL)
+			if err != nil {
+				printErr(err.Error())
+				return
+			}
+			now := time.Now()
+			switch {
+			case e.First.IsZero():
+				fmt.Println("Index:    no documents indexed yet")
+			case e.TTL == 0:
+				fmt.Printf("Index:    first document on %s, does not expire\n", e.First.Local().Format(timeFormat))
+			case e.expired(now):
+				fmt.Printf("Index:    first document on %s, expired %s ago\n",
+					e.First.Local().Format(timeFormat), formatDuration(now.Sub(e.expiresAt())))
+			default:
+				fmt.Printf("Index:    first document on %s, expires in %s, at %s\n",
+					e.First.Local().Format(timeFormat), formatDuration(e.expiresAt().Sub(now)), e.expiresAt().Local().Format(timeFormat))
+			}
+
+			n, err := countMirror(user())
+			if err != nil {
+				printErr(err.Error())
+				return
+			}
+			switch {
+			case s.Mirror:
+				fmt.Printf("Mirror:   %d documents\n", n)
+			case n != 0:
+				fmt.Printf("Mirror:   disabled, %d documents kept from before\n", n)
+			default:
+				fmt.Println("Mirror:   disabled")
+			}
+
+			if e.expired(now) {
+				printReseedHint(user())
+			}
+		},
+	}
+}
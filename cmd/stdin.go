@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+	"github.com/pkg/errors"
+)
+
+const (
+	splitFlag = "split"
+
+	splitNewline = "newline"
+	splitNUL     = "nul"
+
+	// stdinPath stands for the standard input in '--file'
+	stdinPath = "-"
+
+	// maxRecordSize is the size of the longest record of a split stream
+	maxRecordSize = 16 * 1024 * 1024
+)
+
+var splitModes = []string{splitNewline, splitNUL}
+
+// stdinRedirected reports whether the standard input is a pipe or a file
+// rather than a terminal.
+func stdinRedirected() bool {
+	fd := os.Stdin.Fd()
+	return !isatty.IsTerminal(fd) && !isatty.IsCygwinTerminal(fd)
+}
+
+// streamRecords reads the records of r as bulk documents, split on
+// newlines or NUL bytes. Blank records are skipped, a read error is sent
+// as a failed document. Reading stops when ctx is done.
+func streamRecords(ctx context.Context, r io.Reader, split string) <-chan bulkDoc {
+	docs := make(chan bulkDoc)
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, maxRecordSize)
+	if split == splitNUL {
+		sc.Split(scanNUL)
+	}
+
+	go func() {
+		defer close(docs)
+		send := func(doc bulkDoc) bool {
+			select {
+			case docs <- doc:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for n := 1; sc.Scan(); n++ {
+			text := sc.Text()
+			if strings.TrimSpace(text) == "" {
+				continue
+			}
+			if split == splitNewline {
+				text = strings.TrimSuffix(text, "\r")
+			}
+
+			if !send(bulkDoc{Path: fmt.Sprintf("stdin:%d", n), Text: text}) {
+				return
+			}
+		}
+		if errors.Is(sc.Err(), bufio.ErrTooLong) {
+			send(bulkDoc{Path: "stdin", Err: errors.Errorf("a record is longer than %d MiB", maxRecordSize/1024/1024)})
+		} else if err := sc.Err(); err != nil {
+			send(bulkDoc{Path: "stdin", Err: err})
+		}
+	}()
+
+	return docs
+}
+
+// scanNUL is a bufio.SplitFunc for NUL-terminated records,
+// e.g. written by 'find -print0'.
+func scanNUL(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) != 0 {
+		return len(data), data, nil
+	}
+
+	return 0, nil, nil
+}
@@ -0,0 +1,328 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	formatFlag    = "format"
+	textFieldFlag = "text-field"
+	langFieldFlag = "lang-field"
+	idFieldFlag   = "id-field"
+	metaFieldFlag = "meta-field"
+	delimiterFlag = "delimiter"
+
+	formatCSV   = "csv"
+	formatJSONL = "jsonl"
+
+	defaultTextField = "text"
+)
+
+var formats = []string{formatCSV, formatJSONL}
+
+// fieldMap tells which fields of a structured record make up a document.
+// Fields of JSONL objects nested in other objects are joined with dots,
+// e.g. "review.body".
+type fieldMap struct {
+	Text string   `json:"text"`
+	Lang string   `json:"lang,omitempty"`
+	ID   string   `json:"id,omitempty"`
+	Meta []string `json:"meta,omitempty"`
+	// Delimiter separates the fields of CSV rows, a comma by default
+	Delimiter string `json:"delimiter,omitempty"`
+}
+
+// check verifies the settings of m which do not depend on the input.
+func (m fieldMap) check(format string) error {
+	if format != formatCSV && format != formatJSONL {
+		return errors.Errorf("bad '--%s' %q, use one of: %s", formatFlag, format, strings.Join(formats, ", "))
+	}
+	if m.Text == "" {
+		return errors.Errorf("'--%s' must not be empty", textFieldFlag)
+	}
+	if m.Delimiter != "" {
+		if format != formatCSV {
+			return errors.Errorf("'--%s' only applies to the %s format", delimiterFlag, formatCSV)
+		}
+		if _, err := m.comma(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// comma is the CSV delimiter, "tab" and "\t" stand for a tab.
+func (m fieldMap) comma() (rune, error) {
+	switch m.Delimiter {
+	case "":
+		return ',', nil
+	case "tab", `\t`:
+		return '\t', nil
+	}
+	r, size := utf8.DecodeRuneInString(m.Delimiter)
+	if size != len(m.Delimiter) || r == '"' || r == '\r' || r == '\n' || r == utf8.RuneError {
+		return 0, errors.Errorf("bad '--%s' %q, use a single character other than a quote", delimiterFlag, m.Delimiter)
+	}
+
+	return r, nil
+}
+
+// doc turns the fields of a record into a document, lookup returns the
+// value of a field and whether the record has it.
+func (m fieldMap) doc(path string, lookup func(field string) (string, bool)) bulkDoc {
+	doc := bulkDoc{Path: path}
+
+	text, ok := lookup(m.Text)
+	if !ok {
+		doc.Err = errors.Errorf("no %q field", m.Text)
+		return doc
+	}
+	if strings.TrimSpace(text) == "" {
+		doc.Err = errors.Errorf("the %q field is empty", m.Text)
+		return doc
+	}
+	doc.Text = text
+
+	if m.Lang != "" {
+		if lang, _ := lookup(m.Lang); strings.TrimSpace(lang) != "" {
+			doc.Lang = strings.ToLower(strings.TrimSpace(lang))
+			if err := checkLang(doc.Lang); err != nil {
+				doc.Err = err
+				return doc
+			}
+		}
+	}
+	if m.ID != "" {
+		doc.ID, _ = lookup(m.ID)
+		doc.ID = strings.TrimSpace(doc.ID)
+	}
+	for _, field := range m.Meta {
+		if v, _ := lookup(field); v != "" {
+			if doc.Metadata == nil {
+				doc.Metadata = map[string]string{}
+			}
+			doc.Metadata[field] = v
+		}
+	}
+
+	return doc
+}
+
+// streamStructured reads the records of CSV or JSONL inputs as bulk
+// documents, stdinPath being the standard input. Records which cannot
+// be read, and inputs which cannot be read at all, are sent as documents
+// failing with the reason. Reading stops when ctx is done.
+func streamStructured(ctx context.Context, inputs []string, format string, m fieldMap) <-chan bulkDoc {
+	ch := make(chan bulkDoc)
+	send := func(doc bulkDoc) bool {
+		select {
+		case ch <- doc:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	go func() {
+		defer close(ch)
+		for _, input := range inputs {
+			name := input
+			if input == stdinPath {
+				name = "stdin"
+			}
+			err := readStructured(input, name, format, m, send)
+			if err == errStopped {
+				return
+			}
+			if err != nil && !send(bulkDoc{Path: name, Err: err}) {
+				return
+			}
+		}
+	}()
+
+	return ch
+}
+
+// describeInputs names the inputs of a structured index run in messages.
+func describeInputs(inputs []string) string {
+	switch {
+	case len(inputs) == 1 && inputs[0] == stdinPath:
+		return "the standard input"
+	case len(inputs) == 1:
+		return inputs[0]
+	}
+	return fmt.Sprintf("%d files", len(inputs))
+}
+
+func readStructured(input, name, format string, m fieldMap, send func(bulkDoc) bool) error {
+	var r io.Reader = os.Stdin
+	if input != stdinPath {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	if format == formatCSV {
+		return readCSV(r, name, m, send)
+	}
+	return readJSONL(r, name, m, send)
+}
+
+// errStopped tells a record reader was stopped by its consumer.
+var errStopped = errors.New("stopped")
+
+// readCSV sends the rows of a CSV file with a header row. Rows are
+// numbered like in a spreadsheet, the header being row 1.
+func readCSV(r io.Reader, name string, m fieldMap, send func(bulkDoc) bool) error {
+	cr := csv.NewReader(r)
+	cr.Comma, _ = m.comma()
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return errors.New("no header row")
+	}
+	if err != nil {
+		return errors.WithMessage(err, "bad header row")
+	}
+	columns := map[string]int{}
+	for i, col := range header {
+		// spreadsheet exports often start with a byte order mark
+		if i == 0 {
+			col = strings.TrimPrefix(col, "\ufeff")
+		}
+		col = strings.TrimSpace(col)
+		if _, ok := columns[col]; !ok {
+			columns[col] = i
+		}
+	}
+	for _, field := range append([]string{m.Text, m.Lang, m.ID}, m.Meta...) {
+		if _, ok := columns[field]; field != "" && !ok {
+			return errors.Errorf("no %q column in the header row", field)
+		}
+	}
+
+	for row := 2; ; row++ {
+		path := fmt.Sprintf("%s:%d", name, row)
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			if !send(bulkDoc{Path: path, Err: parseErr.Err}) {
+				return errStopped
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if blankRecord(rec) {
+			continue
+		}
+
+		doc := m.doc(path, func(field string) (string, bool) {
+			i := columns[field]
+			if i >= len(rec) {
+				return "", false
+			}
+			return rec[i], true
+		})
+		if !send(doc) {
+			return errStopped
+		}
+	}
+}
+
+func blankRecord(rec []string) bool {
+	for _, v := range rec {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// readJSONL sends the objects of a file with a JSON object per line.
+func readJSONL(r io.Reader, name string, m fieldMap, send func(bulkDoc) bool) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, maxRecordSize)
+	for line := 1; sc.Scan(); line++ {
+		data := bytes.TrimSpace(sc.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		path := fmt.Sprintf("%s:%d", name, line)
+
+		var obj map[string]interface{}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&obj); err != nil {
+			if !send(bulkDoc{Path: path, Err: errors.WithMessage(err, "malformed JSON object")}) {
+				return errStopped
+			}
+			continue
+		}
+
+		doc := m.doc(path, func(field string) (string, bool) {
+			return jsonField(obj, field)
+		})
+		if !send(doc) {
+			return errStopped
+		}
+	}
+	if errors.Is(sc.Err(), bufio.ErrTooLong) {
+		return errors.Errorf("a line is longer than %d MiB", maxRecordSize/1024/1024)
+	}
+
+	return sc.Err()
+}
+
+// jsonField returns the value of a field of obj as text, following dots
+// into nested objects. Objects and arrays are returned as JSON.
+func jsonField(obj map[string]interface{}, field string) (string, bool) {
+	v, ok := obj[field]
+	if !ok {
+		i := strings.Index(field, ".")
+		if i < 0 {
+			return "", false
+		}
+		nested, isObj := obj[field[:i]].(map[string]interface{})
+		if !isObj {
+			return "", false
+		}
+		return jsonField(nested, field[i+1:])
+	}
+
+	switch v := v.(type) {
+	case nil:
+		return "", true
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	default:
+		data, err := json.Marshal(v)
+		return string(data), err == nil
+	}
+}
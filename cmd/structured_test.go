@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// collect returns the documents read from a structured input.
+func collect(t *testing.T, format, input string, m fieldMap) ([]bulkDoc, error) {
+	t.Helper()
+	var docs []bulkDoc
+	send := func(doc bulkDoc) bool {
+		docs = append(docs, doc)
+		return true
+	}
+
+	var err error
+	if format == formatCSV {
+		err = readCSV(strings.NewReader(input), "in", m, send)
+	} else {
+		err = readJSONL(strings.NewReader(input), "in", m, send)
+	}
+
+	return docs, err
+}
+
+// docErrors flattens the errors of docs for comparisons.
+func docErrors(docs []bulkDoc) []string {
+	errs := make([]string, len(docs))
+	for i := range docs {
+		if docs[i].Err != nil {
+			errs[i] = docs[i].Err.Error()
+			docs[i].Err = nil
+		}
+	}
+
+	return errs
+}
+
+func TestReadCSV(t *testing.T) {
+	input := "\ufeffid, text ,lang,stars\n" +
+		"r1,The weather is good.,EN,5\n" +
+		"r2,\"Quoted, with a comma\nand a line break\",,4\n" +
+		",,,\n" +
+		"r3,   ,en,1\n" +
+		"r4,Bad language,xx,2\n" +
+		"r5\n"
+	m := fieldMap{Text: "text", Lang: "lang", ID: "id", Meta: []string{"stars"}}
+
+	docs, err := collect(t, formatCSV, input, m)
+	if err != nil {
+		t.Fatalf("readCSV() error = %v", err)
+	}
+	errs := docErrors(docs)
+	want := []bulkDoc{
+		{Path: "in:2", Text: "The weather is good.", Lang: "en", ID: "r1", Metadata: map[string]string{"stars": "5"}},
+		{Path: "in:3", Text: "Quoted, with a comma\nand a line break", ID: "r2", Metadata: map[string]string{"stars": "4"}},
+		// rows are records, as in a spreadsheet, not lines
+		{Path: "in:5"},
+		{Path: "in:6", Text: "Bad language", Lang: "xx"},
+		{Path: "in:7"},
+	}
+	wantErrs := []string{"", "", `the "text" field is empty`, `unsupported language "xx"`, `no "text" field`}
+
+	if !reflect.DeepEqual(docs, want) {
+		t.Errorf("readCSV() = %+v, want %+v", docs, want)
+	}
+	for i := range wantErrs {
+		if i >= len(errs) || !strings.HasPrefix(errs[i], wantErrs[i]) {
+			t.Errorf("readCSV() errors = %q, want %q", errs, wantErrs)
+			break
+		}
+	}
+}
+
+func TestReadCSVErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		m     fieldMap
+		want  string
+	}{
+		{"empty", "", fieldMap{Text: "text"}, "no header row"},
+		{"missing column", "body,lang\nText,en\n", fieldMap{Text: "text"}, `no "text" column in the header row`},
+		{"missing id column", "text\nText\n", fieldMap{Text: "text", ID: "id"}, `no "id" column in the header row`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := collect(t, formatCSV, tt.input, tt.m); err == nil || err.Error() != tt.want {
+				t.Errorf("readCSV() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	// a malformed row fails on its own
+	docs, err := collect(t, formatCSV, "text\n\"unterminated\nText\n", fieldMap{Text: "text"})
+	if err != nil || len(docs) != 1 || docs[0].Err == nil {
+		t.Errorf("readCSV() = %+v, %v, want a failing row", docs, err)
+	}
+}
+
+func TestReadCSVDelimiter(t *testing.T) {
+	docs, err := collect(t, formatCSV, "text\tid\nSunny, warm.\ta\n", fieldMap{Text: "text", ID: "id", Delimiter: "tab"})
+	if err != nil || len(docs) != 1 || docs[0].Text != "Sunny, warm." || docs[0].ID != "a" {
+		t.Errorf("readCSV() = %+v, %v", docs, err)
+	}
+}
+
+func TestReadJSONL(t *testing.T) {
+	input := `{"id": 7, "review": {"body": "The weather is good.", "stars": 5}, "lang": "en", "tags": ["a", "b"], "ok": true}` + "\n" +
+		"\n" +
+		`{"review": {"body": ""}}` + "\n" +
+		`not json` + "\n" +
+		`{"review": "flat"}` + "\n"
+	m := fieldMap{Text: "review.body", Lang: "lang", ID: "id", Meta: []string{"review.stars", "tags", "ok", "missing"}}
+
+	docs, err := collect(t, formatJSONL, input, m)
+	if err != nil {
+		t.Fatalf("readJSONL() error = %v", err)
+	}
+	errs := docErrors(docs)
+	want := []bulkDoc{
+		{Path: "in:1", Text: "The weather is good.", Lang: "en", ID: "7", Metadata: map[string]string{"review.stars": "5", "tags": `["a","b"]`, "ok": "true"}},
+		{Path: "in:3"},
+		{Path: "in:4"},
+		{Path: "in:5"},
+	}
+	wantErrs := []string{"", `the "review.body" field is empty`, "malformed JSON object", `no "review.body" field`}
+
+	if !reflect.DeepEqual(docs, want) {
+		t.Errorf("readJSONL() = %+v, want %+v", docs, want)
+	}
+	for i := range wantErrs {
+		if i >= len(errs) || !strings.HasPrefix(errs[i], wantErrs[i]) {
+			t.Errorf("readJSONL() errors = %q, want %q", errs, wantErrs)
+			break
+		}
+	}
+}
+
+func TestFieldMapCheck(t *testing.T) {
+	tests := []struct {
+		format string
+		m      fieldMap
+		want   string
+	}{
+		{formatCSV, fieldMap{Text: "text"}, ""},
+		{formatCSV, fieldMap{Text: "text", Delimiter: ";"}, ""},
+		{"xml", fieldMap{Text: "text"}, `bad '--format' "xml", use one of: csv, jsonl`},
+		{formatCSV, fieldMap{}, "'--text-field' must not be empty"},
+		{formatJSONL, fieldMap{Text: "text", Delimiter: ";"}, "'--delimiter' only applies to the csv format"},
+		{formatCSV, fieldMap{Text: "text", Delimiter: `"`}, `bad '--delimiter' "\"", use a single character other than a quote`},
+		{formatCSV, fieldMap{Text: "text", Delimiter: ";;"}, `bad '--delimiter' ";;", use a single character other than a quote`},
+	}
+
+	for _, tt := range tests {
+		err := tt.m.check(tt.format)
+		if got := ""; err != nil {
+			got = err.Error()
+			if got != tt.want {
+				t.Errorf("check(%q, %+v) = %q, want %q", tt.format, tt.m, got, tt.want)
+			}
+		} else if tt.want != "" {
+			t.Errorf("check(%q, %+v) succeeded, want %q", tt.format, tt.m, tt.want)
+		}
+	}
+}
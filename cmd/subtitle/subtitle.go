@@ -0,0 +1,212 @@
+// Package subtitle reads the cues of SubRip (.srt) and WebVTT (.vtt)
+// subtitles and transcripts, and groups them into windows of time so
+// that a search match can be located in the recording.
+//
+// Importing the package registers both formats with the extract package,
+// the text of a subtitle file being the text of its cues.
+package subtitle
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/repustate/rcli/cmd/extract"
+)
+
+// MIME types of subtitle files.
+const (
+	TypeSRT = "application/x-subrip"
+	TypeVTT = "text/vtt"
+)
+
+func init() {
+	extract.Register(TypeSRT, text, ".srt")
+	extract.Register(TypeVTT, text, ".vtt")
+}
+
+// Cue is a piece of text shown from Start to End of a recording.
+type Cue struct {
+	Start, End time.Duration
+	Text       string
+}
+
+var (
+	// timing lines, "00:12:31,500 --> 00:12:34,000" in SubRip and
+	// "12:31.500 --> 12:34.000 align:start" in WebVTT
+	timing = regexp.MustCompile(`^\s*((?:\d+:)?\d{1,2}:\d{2}[,.]\d{1,3})\s*-->\s*((?:\d+:)?\d{1,2}:\d{2}[,.]\d{1,3})`)
+
+	// markup of cue text: HTML-like tags, WebVTT timestamps and voices,
+	// and the {\an8} positioning of SubRip
+	markup = regexp.MustCompile(`<[^>]*>|\{\\[^}]*\}`)
+	voice  = regexp.MustCompile(`<v(?:\.[^ >]*)?\s+([^>]+)>`)
+)
+
+// Is tells whether the MIME type of a file is a subtitle type.
+func Is(mimeType string) bool {
+	return mimeType == TypeSRT || mimeType == TypeVTT
+}
+
+// Parse returns the cues of SubRip or WebVTT subtitles, in the order of
+// the file. Blocks without a timing line, such as WebVTT notes, styles
+// and regions, are left out.
+func Parse(data []byte) ([]Cue, error) {
+	text := strings.TrimPrefix(string(data), "\ufeff")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	var cues []Cue
+	for _, block := range splitBlocks(text) {
+		lines := strings.Split(block, "\n")
+		// the timing line may follow a cue number or identifier
+		i := 0
+		for i < len(lines) && i < 2 && !timing.MatchString(lines[i]) {
+			i++
+		}
+		if i == len(lines) || !timing.MatchString(lines[i]) {
+			// the WebVTT header and NOTE, STYLE and REGION blocks
+			continue
+		}
+
+		m := timing.FindStringSubmatch(lines[i])
+		start, err := parseTimecode(m[1])
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseTimecode(m[2])
+		if err != nil {
+			return nil, err
+		}
+
+		var text []string
+		for _, line := range lines[i+1:] {
+			line = voice.ReplaceAllString(line, "$1: ")
+			line = html.UnescapeString(markup.ReplaceAllString(line, ""))
+			if line = strings.Join(strings.Fields(line), " "); line != "" {
+				text = append(text, line)
+			}
+		}
+		if len(text) != 0 {
+			cues = append(cues, Cue{Start: start, End: end, Text: strings.Join(text, "\n")})
+		}
+	}
+	if len(cues) == 0 {
+		return nil, errors.New("no subtitle cues")
+	}
+
+	return cues, nil
+}
+
+// splitBlocks returns the blocks of text separated by blank lines.
+func splitBlocks(text string) []string {
+	var blocks []string
+	var block []string
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			if len(block) != 0 {
+				blocks = append(blocks, strings.Join(block, "\n"))
+				block = nil
+			}
+			continue
+		}
+		block = append(block, line)
+	}
+	if len(block) != 0 {
+		blocks = append(blocks, strings.Join(block, "\n"))
+	}
+
+	return blocks
+}
+
+// parseTimecode reads "hh:mm:ss,mmm", or "mm:ss.mmm" in WebVTT.
+func parseTimecode(s string) (time.Duration, error) {
+	s = strings.Replace(s, ",", ".", 1)
+	parts := strings.Split(s, ":")
+	var d time.Duration
+	for i, part := range parts {
+		unit := time.Minute
+		if len(parts)-i == 3 {
+			unit = time.Hour
+		}
+		if i == len(parts)-1 {
+			secs, err := strconv.ParseFloat(part, 64)
+			if err != nil {
+				return 0, errors.Errorf("bad timecode %q", s)
+			}
+			d += time.Duration(secs * float64(time.Second))
+			break
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return 0, errors.Errorf("bad timecode %q", s)
+		}
+		d += time.Duration(n) * unit
+	}
+
+	return d.Round(time.Millisecond), nil
+}
+
+// Timecode formats d as "hh:mm:ss", the way players show positions.
+func Timecode(d time.Duration) string {
+	secs := int(d / time.Second)
+	return fmt.Sprintf("%02d:%02d:%02d", secs/3600, secs/60%60, secs%60)
+}
+
+// Windows groups consecutive cues into windows of at most the given
+// length, longer cues making a window of their own. Each cue is a window
+// when length is 0. Lines repeated by rolling captions are only kept once
+// within a window.
+func Windows(cues []Cue, length time.Duration) []Cue {
+	var windows []Cue
+	var lastLine string
+	for _, c := range cues {
+		n := len(windows)
+		if n == 0 || length <= 0 || c.End-windows[n-1].Start > length {
+			windows = append(windows, Cue{Start: c.Start, End: c.End})
+			n++
+			// every window has the whole text of its cues
+			lastLine = ""
+		}
+
+		w := &windows[n-1]
+		if c.End > w.End {
+			w.End = c.End
+		}
+		for _, line := range strings.Split(c.Text, "\n") {
+			if line == lastLine {
+				continue
+			}
+			lastLine = line
+			if w.Text != "" {
+				w.Text += "\n"
+			}
+			w.Text += line
+		}
+	}
+
+	return windows
+}
+
+// text returns the text of the cues of a subtitle file, for extract.
+func text(data []byte) (string, error) {
+	cues, err := Parse(data)
+	if err != nil {
+		return "", err
+	}
+
+	// the lines repeated by rolling captions are only kept once
+	var lines []string
+	for _, c := range cues {
+		for _, line := range strings.Split(c.Text, "\n") {
+			if len(lines) == 0 || line != lines[len(lines)-1] {
+				lines = append(lines, line)
+			}
+		}
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
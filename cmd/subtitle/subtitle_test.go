@@ -0,0 +1,142 @@
+package subtitle
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/repustate/rcli/cmd/extract"
+)
+
+const srt = "\ufeff1\r\n" +
+	"00:00:01,000 --> 00:00:04,500\r\n" +
+	"<i>Good evening</i> and welcome.\r\n" +
+	"\r\n" +
+	"2\r\n" +
+	"00:00:05,000 --> 00:00:08,250\r\n" +
+	"{\\an8}Tonight: the weather\r\n" +
+	"in London &amp; Paris.\r\n" +
+	"\r\n" +
+	"3\r\n" +
+	"01:02:03,004 --> 01:02:05,000\r\n" +
+	"Goodbye.\r\n"
+
+const vtt = `WEBVTT - evening news
+
+NOTE recorded live
+
+STYLE
+::cue { color: yellow }
+
+intro
+00:01.000 --> 00:04.000 align:start
+<v.host Ann>Good evening.
+
+00:04.000 --> 00:07.000
+<c.loud>Rain</c> is <00:05.000>coming.
+`
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []Cue
+	}{
+		{"srt", srt, []Cue{
+			{Start: time.Second, End: 4500 * time.Millisecond, Text: "Good evening and welcome."},
+			{Start: 5 * time.Second, End: 8250 * time.Millisecond, Text: "Tonight: the weather\nin London & Paris."},
+			{Start: time.Hour + 2*time.Minute + 3004*time.Millisecond, End: time.Hour + 2*time.Minute + 5*time.Second, Text: "Goodbye."},
+		}},
+		{"vtt", vtt, []Cue{
+			{Start: time.Second, End: 4 * time.Second, Text: "Ann: Good evening."},
+			{Start: 4 * time.Second, End: 7 * time.Second, Text: "Rain is coming."},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cues, err := Parse([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !reflect.DeepEqual(cues, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", cues, tt.want)
+			}
+		})
+	}
+
+	if _, err := Parse([]byte("WEBVTT\n\nNOTE nothing to show\n")); err == nil {
+		t.Error("Parse() of subtitles without cues succeeded")
+	}
+}
+
+func TestWindows(t *testing.T) {
+	// rolling captions repeat the line shown last
+	cues := []Cue{
+		{Start: 0, End: 2 * time.Second, Text: "the weather today"},
+		{Start: 2 * time.Second, End: 4 * time.Second, Text: "the weather today\nis sunny"},
+		{Start: 4 * time.Second, End: 6 * time.Second, Text: "is sunny\nand warm"},
+		{Start: 6 * time.Second, End: 8 * time.Second, Text: "and warm\nin Paris"},
+		{Start: 20 * time.Second, End: 50 * time.Second, Text: "a long cue"},
+	}
+
+	tests := []struct {
+		length time.Duration
+		want   []Cue
+	}{
+		{5 * time.Second, []Cue{
+			{Start: 0, End: 4 * time.Second, Text: "the weather today\nis sunny"},
+			// a window starting on a repeated line keeps it
+			{Start: 4 * time.Second, End: 8 * time.Second, Text: "is sunny\nand warm\nin Paris"},
+			{Start: 20 * time.Second, End: 50 * time.Second, Text: "a long cue"},
+		}},
+		{30 * time.Second, []Cue{
+			{Start: 0, End: 8 * time.Second, Text: "the weather today\nis sunny\nand warm\nin Paris"},
+			{Start: 20 * time.Second, End: 50 * time.Second, Text: "a long cue"},
+		}},
+		{0, []Cue{
+			{Start: 0, End: 2 * time.Second, Text: "the weather today"},
+			{Start: 2 * time.Second, End: 4 * time.Second, Text: "the weather today\nis sunny"},
+			{Start: 4 * time.Second, End: 6 * time.Second, Text: "is sunny\nand warm"},
+			{Start: 6 * time.Second, End: 8 * time.Second, Text: "and warm\nin Paris"},
+			{Start: 20 * time.Second, End: 50 * time.Second, Text: "a long cue"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.length.String(), func(t *testing.T) {
+			if got := Windows(cues, tt.length); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Windows() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimecode(t *testing.T) {
+	tests := map[time.Duration]string{
+		0:                                     "00:00:00",
+		59*time.Second + 999*time.Millisecond: "00:00:59",
+		time.Hour + 2*time.Minute + 3*time.Second: "01:02:03",
+	}
+	for d, want := range tests {
+		if got := Timecode(d); got != want {
+			t.Errorf("Timecode(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	data := "1\n00:00:00,000 --> 00:00:02,000\nthe weather today\n\n" +
+		"2\n00:00:02,000 --> 00:00:04,000\nthe weather today\nis sunny\n"
+
+	text, mimeType, err := extract.Text("news.srt", []byte(data))
+	if err != nil {
+		t.Fatalf("Text() error = %v", err)
+	}
+	if mimeType != TypeSRT || text != "the weather today\nis sunny" {
+		t.Errorf("Text() = %q, %q", text, mimeType)
+	}
+	if _, mimeType, _ := extract.Text("news.vtt", []byte(vtt)); !Is(mimeType) {
+		t.Errorf("Text() type of a vtt file = %q", mimeType)
+	}
+}
@@ -1,18 +1,24 @@
 package cmd
 
 import (
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"runtime"
 
 	"github.com/fatih/color"
+
+	api "github.com/repustate/rcli/api-client/v4"
 )
 
 const (
 	profileFilename = ".repustate"
+	appDirname      = "rcli"
 )
 
+// loadUserUUID reads the user UUID of rcli versions without identities,
+// it is imported as the "default" identity.
 func loadUserUUID() string {
 	f := filepath.Join(getHomeDir(), profileFilename)
 	data, err := ioutil.ReadFile(f)
@@ -23,19 +29,6 @@ func loadUserUUID() string {
 	return string(data)
 }
 
-func storeUserUUID(uuid string) error {
-	filename := filepath.Join(getHomeDir(), profileFilename)
-
-	f, err := os.Create(filename)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	_, err = f.WriteString(uuid)
-	return err
-}
-
 func getHomeDir() string {
 	dir, err := os.UserHomeDir()
 	if err == nil {
@@ -52,14 +45,78 @@ func getHomeDir() string {
 	return os.Getenv("HOME")
 }
 
+// getConfigDir returns the directory rcli keeps its configuration in,
+// e.g. $XDG_CONFIG_HOME/rcli on Linux.
+func getConfigDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = filepath.Join(getHomeDir(), ".config")
+	}
+
+	return filepath.Join(dir, appDirname)
+}
+
+// writeFileAtomic replaces filename with data, readable by the owner only.
+// The data is written to a temporary file first, so readers never see
+// a partially written file.
+func writeFileAtomic(filename string, data []byte) error {
+	dir := filepath.Dir(filename)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(dir, "."+filepath.Base(filename)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if err := f.Chmod(0600); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), filename)
+}
+
+// printErrHint suggests what to do about a failed API call. badRequest
+// tells what to check when the server rejected the request content.
+func printErrHint(err error, badRequest string) {
+	switch {
+	case api.IsRateLimited(err):
+		printMsg("The server is rate limiting requests, wait a minute and try again.")
+	case api.IsBadRequest(err):
+		printMsg(badRequest)
+	case api.IsNotFound(err):
+		printMsg("The API endpoint was not found, check the server address and base path of the selected '--profile'.")
+	}
+}
+
+// printMsg prints an informational message, to stderr when the
+// output is meant for other programs.
 func printMsg(s string) {
-	printColor(color.FgBlue, s)
+	w := color.Output
+	if output.machine() {
+		w = color.Error
+	}
+	printColor(w, color.FgBlue, s)
 }
 
 func printErr(s string) {
-	printColor(color.FgRed, s)
+	printColor(color.Error, color.FgRed, s)
 }
 
-func printColor(c color.Attribute, s string) {
-	color.New(c).Println(s)
+func printColor(w io.Writer, c color.Attribute, s string) {
+	color.New(c).Fprintln(w, s)
 }
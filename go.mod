@@ -1,12 +1,22 @@
 module github.com/repustate/rcli
 
-go 1.14
+go 1.17
 
 require (
 	github.com/fatih/color v1.10.0
 	github.com/google/uuid v1.1.2
+	github.com/mattn/go-isatty v0.0.12
 	github.com/pkg/errors v0.9.1
 	github.com/spf13/cobra v1.1.1
-	golang.org/x/crypto v0.0.0-20201016220609-9e8e0b390897 // indirect
-	golang.org/x/net v0.0.0-20201031054903-ff519b6c9102 // indirect
+	go.etcd.io/bbolt v1.3.6
+	golang.org/x/term v0.0.0-20201210144234-2321bbc49cbf
+	golang.org/x/text v0.13.0
+	gopkg.in/yaml.v2 v2.2.8
+)
+
+require (
+	github.com/inconshreveable/mousetrap v1.0.0 // indirect
+	github.com/mattn/go-colorable v0.1.8 // indirect
+	github.com/spf13/pflag v1.0.5 // indirect
+	golang.org/x/sys v0.7.0 // indirect
 )